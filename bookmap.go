@@ -0,0 +1,141 @@
+package inpx
+
+import (
+	"fmt"
+	"time"
+)
+
+// Keys used by Book.ToMap and Book.FromMap. They are named after the field constants.
+const (
+	KeyAuthor    = "Author"
+	KeyGenre     = "Genre"
+	KeyTitle     = "Title"
+	KeySeries    = "Series"
+	KeySeriesNum = "SeriesNum"
+	KeyFileName  = "FileName"
+	KeyFileSize  = "FileSize"
+	KeyLibId     = "LibId"
+	KeyDeleted   = "Deleted"
+	KeyExt       = "Ext"
+	KeyDate      = "Date"
+	KeyLang      = "Lang"
+	KeyLibRate   = "LibRate"
+	KeyISBN      = "ISBN"
+	KeyKeywords  = "Keywords"
+)
+
+// ToMap returns all non-empty fields of the book keyed by field name (see KeyAuthor, etc).
+// Empty strings and slices, zero numbers and dates, false flags and unset LibRate are omitted.
+func (b Book) ToMap() map[string]interface{} {
+	m := make(map[string]interface{})
+	setStr := func(k, v string) {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	setInt := func(k string, v int) {
+		if v != 0 {
+			m[k] = v
+		}
+	}
+	if len(b.Authors) != 0 {
+		m[KeyAuthor] = append([]Author(nil), b.Authors...)
+	}
+	if len(b.Genres) != 0 {
+		m[KeyGenre] = append([]string(nil), b.Genres...)
+	}
+	setStr(KeyTitle, b.Title)
+	setStr(KeySeries, b.Series)
+	setInt(KeySeriesNum, b.SeriesNum)
+	setStr(KeyFileName, b.File.Name)
+	setInt(KeyFileSize, b.File.Size)
+	setInt(KeyLibId, b.LibId)
+	if b.Deleted {
+		m[KeyDeleted] = true
+	}
+	setStr(KeyExt, b.File.Ext)
+	if !b.Date.IsZero() {
+		m[KeyDate] = b.Date
+	}
+	setStr(KeyLang, b.Lang)
+	if b.LibRate >= 0 {
+		m[KeyLibRate] = b.LibRate
+	}
+	setStr(KeyISBN, b.ISBN)
+	if len(b.Keywords) != 0 {
+		m[KeyKeywords] = append([]string(nil), b.Keywords...)
+	}
+	return m
+}
+
+// FromMap replaces the book with fields from the map returned by ToMap.
+// Missing keys leave fields empty. Integer fields also accept int64 and float64 values.
+func (b *Book) FromMap(m map[string]interface{}) error {
+	out := Book{LibRate: -1}
+	for k, v := range m {
+		var ok bool
+		switch k {
+		case KeyAuthor:
+			var arr []Author
+			if arr, ok = v.([]Author); ok {
+				out.Authors = append([]Author(nil), arr...)
+			}
+		case KeyGenre:
+			var arr []string
+			if arr, ok = v.([]string); ok {
+				out.Genres = append([]string(nil), arr...)
+			}
+		case KeyTitle:
+			out.Title, ok = v.(string)
+		case KeySeries:
+			out.Series, ok = v.(string)
+		case KeySeriesNum:
+			out.SeriesNum, ok = mapInt(v)
+		case KeyFileName:
+			out.File.Name, ok = v.(string)
+		case KeyFileSize:
+			out.File.Size, ok = mapInt(v)
+		case KeyLibId:
+			out.LibId, ok = mapInt(v)
+		case KeyDeleted:
+			out.Deleted, ok = v.(bool)
+		case KeyExt:
+			out.File.Ext, ok = v.(string)
+		case KeyDate:
+			out.Date, ok = v.(time.Time)
+		case KeyLang:
+			out.Lang, ok = v.(string)
+		case KeyLibRate:
+			out.LibRate, ok = mapInt(v)
+		case KeyISBN:
+			out.ISBN, ok = v.(string)
+		case KeyKeywords:
+			var arr []string
+			if arr, ok = v.([]string); ok {
+				out.Keywords = append([]string(nil), arr...)
+			}
+		default:
+			return fmt.Errorf("unknown book field: %q", k)
+		}
+		if !ok {
+			return fmt.Errorf("unexpected type for book field %q: %T", k, v)
+		}
+	}
+	*b = out
+	return nil
+}
+
+func mapInt(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		if v != float64(int(v)) {
+			return 0, false
+		}
+		return int(v), true
+	}
+	return 0, false
+}
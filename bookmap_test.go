@@ -0,0 +1,58 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBookMap(t *testing.T) {
+	b := Book{
+		Authors: []Author{newAuthor([]string{"Doe", "John"})},
+		Genres:  []string{"sf"},
+		Title:   "Title",
+		File:    File{Name: "1", Ext: "fb2", Size: 10},
+		LibId:   1,
+		Date:    testDate,
+		LibRate: 0,
+	}
+	m := b.ToMap()
+	for _, k := range []string{KeySeries, KeySeriesNum, KeyDeleted, KeyLang} {
+		if _, ok := m[k]; ok {
+			t.Fatalf("empty field %q is not omitted", k)
+		}
+	}
+	if m[KeyLibRate] != 0 || m[KeyTitle] != "Title" {
+		t.Fatalf("unexpected map: %v", m)
+	}
+	var b2 Book
+	if err := b2.FromMap(m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b, b2) {
+		t.Fatalf("unexpected book:\n%+v\nvs\n%+v", b2, b)
+	}
+	if err := b2.FromMap(map[string]interface{}{KeyLibId: 2.0}); err != nil {
+		t.Fatal(err)
+	} else if b2.LibId != 2 || b2.LibRate != -1 || b2.Title != "" {
+		t.Fatalf("unexpected book: %+v", b2)
+	}
+	if err := b2.FromMap(map[string]interface{}{KeyTitle: 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if err := b2.FromMap(map[string]interface{}{"Unknown": 1}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestBookStrings(t *testing.T) {
+	b := Book{
+		Authors: []Author{newAuthor([]string{"Doe", "John"}), newAuthor([]string{"Roe"})},
+		Genres:  []string{"sf", "det"},
+	}
+	if s := b.AuthorsStr(); s != "John Doe;Roe" {
+		t.Fatalf("unexpected authors: %q", s)
+	}
+	if s := b.GenresStr(); s != "sf;det" {
+		t.Fatalf("unexpected genres: %q", s)
+	}
+}
@@ -0,0 +1,39 @@
+package inpx
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteCSVSummary writes a CSV report with one line per archive: archive name, number of books
+// and a sorted comma-separated list of normalized language codes used in the archive.
+// Archives are written in natural order (see NaturalLess).
+func (idx *Index) WriteCSVSummary(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"archive", "books", "languages"}); err != nil {
+		return err
+	}
+	for _, name := range idx.SortedArchiveNames() {
+		books := idx.Archives[name]
+		seen := make(map[string]struct{})
+		var langs []string
+		for _, b := range books {
+			lang := b.LangNormalized()
+			if _, ok := seen[lang]; ok || lang == "" {
+				continue
+			}
+			seen[lang] = struct{}{}
+			langs = append(langs, lang)
+		}
+		sort.Strings(langs)
+		rec := []string{name, strconv.Itoa(len(books)), strings.Join(langs, ",")}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,21 @@
+package inpx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteCSVSummary(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"fb2-10": {{Lang: "ru"}, {Lang: "en"}, {Lang: "rus"}},
+		"fb2-9":  {{Lang: ""}},
+	}}
+	var buf bytes.Buffer
+	if err := idx.WriteCSVSummary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := "archive,books,languages\nfb2-9,1,\nfb2-10,3,\"en,ru\"\n"
+	if buf.String() != exp {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
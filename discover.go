@@ -0,0 +1,44 @@
+package inpx
+
+import (
+	"archive/zip"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// IsINPX checks if the file is a library index: it either has the ".inpx" extension,
+// or it is a zip file with collection.info. The latter is used by some tools that
+// name index files with the ".zip" extension.
+func IsINPX(path string) bool {
+	if strings.EqualFold(filepath.Ext(path), ".inpx") {
+		return true
+	}
+	zf, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer zf.Close()
+	for _, f := range zf.File {
+		if f.Name == "collection.info" {
+			return true
+		}
+	}
+	return false
+}
+
+// DiscoverINPX walks the directory recursively and returns paths of all library index files
+// (see IsINPX) in lexical order.
+func DiscoverINPX(dir string) ([]string, error) {
+	var out []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && IsINPX(path) {
+			out = append(out, path)
+		}
+		return nil
+	})
+	return out, err
+}
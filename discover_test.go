@@ -0,0 +1,29 @@
+package inpx
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiscoverINPX(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestZip(t, filepath.Join(dir, "a.inpx"), map[string]string{"a.inp": ""})
+	writeTestZip(t, filepath.Join(dir, "sub", "b.zip"), map[string]string{"collection.info": "Test\n"})
+	writeTestZip(t, filepath.Join(dir, "sub", "books.zip"), map[string]string{"1.fb2": "book"})
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := DiscoverINPX(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{filepath.Join(dir, "a.inpx"), filepath.Join(dir, "sub", "b.zip")}
+	if !reflect.DeepEqual(paths, exp) {
+		t.Fatalf("unexpected paths: %q", paths)
+	}
+}
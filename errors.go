@@ -0,0 +1,67 @@
+package inpx
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrStale is returned when a cached index is older than the source inpx file.
+	ErrStale = errors.New("index cache is stale")
+	// ErrTruncated is returned when an inp record has fewer fields than expected.
+	ErrTruncated = errors.New("truncated record")
+	// ErrNoSignature is returned when a file lacks an expected signature (magic header).
+	ErrNoSignature = errors.New("no signature")
+	// ErrNoCover is returned when a book has no cover image.
+	ErrNoCover = errors.New("no cover")
+	// ErrUnsupportedFormat is returned for files in a format the package cannot read.
+	ErrUnsupportedFormat = errors.New("unsupported format")
+	// ErrSplitFile is returned when raw access is requested for a file that is split
+	// across several archives (see File.AdditionalArchives).
+	ErrSplitFile = errors.New("file is split across several archives")
+)
+
+// ParseError describes a record of inp file that cannot be parsed.
+type ParseError struct {
+	Archive string // name of the archive (inp file without extension)
+	Line    int    // line number, starting from 1
+	Raw     []byte // raw line without the line separator
+	Cause   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s.inp:%d: %v", e.Archive, e.Line, e.Cause)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Cause
+}
+
+// ArchiveOpenError is returned when a book archive cannot be opened.
+type ArchiveOpenError struct {
+	Archive string // name of the archive, see File.Archive
+	Cause   error
+}
+
+func (e *ArchiveOpenError) Error() string {
+	return fmt.Sprintf("error while opening archive %s: %v", e.Archive, e.Cause)
+}
+
+func (e *ArchiveOpenError) Unwrap() error {
+	return e.Cause
+}
+
+// MissingFileError is returned when a book file is not found in the archive.
+// It matches os.ErrNotExist with errors.Is.
+type MissingFileError struct {
+	File File
+}
+
+func (e *MissingFileError) Error() string {
+	return fmt.Sprintf("file %s is not found", e.File.RelativePath())
+}
+
+func (e *MissingFileError) Is(target error) bool {
+	return target == os.ErrNotExist
+}
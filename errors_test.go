@@ -0,0 +1,102 @@
+package inpx
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorTypes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "a.zip"), map[string]string{"1.fb2": "book"})
+	open := func(fr File) func() error {
+		return func() error {
+			rc, err := fr.Open()
+			if err == nil {
+				rc.Close()
+			}
+			return err
+		}
+	}
+	for _, c := range []struct {
+		name     string
+		fn       func() error
+		check    func(err error) bool
+		notExist bool
+	}{
+		{
+			name: "missing archive",
+			fn:   open(File{Name: "1", Ext: "fb2", Dir: dir, Archive: "b"}),
+			check: func(err error) bool {
+				var e *ArchiveOpenError
+				return errors.As(err, &e) && e.Archive == "b"
+			},
+			notExist: true,
+		},
+		{
+			name: "missing archive fast",
+			fn: func() error {
+				_, err := File{Name: "1", Ext: "fb2", Dir: dir, Archive: "b", Offset: 10}.OpenFast()
+				return err
+			},
+			check: func(err error) bool {
+				var e *ArchiveOpenError
+				return errors.As(err, &e) && e.Archive == "b"
+			},
+			notExist: true,
+		},
+		{
+			name: "missing file",
+			fn:   open(File{Name: "2", Ext: "fb2", Dir: dir, Archive: "a"}),
+			check: func(err error) bool {
+				var e *MissingFileError
+				return errors.As(err, &e) && e.File.Name == "2"
+			},
+			notExist: true,
+		},
+		{
+			name: "missing file in pool",
+			fn: func() error {
+				pool := NewArchivePool(1)
+				defer pool.Close()
+				_, err := File{Name: "2", Ext: "fb2", Dir: dir, Archive: "a"}.OpenVia(pool)
+				return err
+			},
+			check: func(err error) bool {
+				var e *MissingFileError
+				return errors.As(err, &e) && e.File.Archive == "a"
+			},
+			notExist: true,
+		},
+		{
+			name: "truncated record",
+			fn: func() error {
+				var perr error
+				r := newInpReader(strings.NewReader("Title\n"), "a", "", DefaultStructure, newConfig([]Option{
+					WithErrorHandler(func(err error) { perr = err }),
+				}))
+				if _, err := r.next(); err != io.EOF {
+					return err
+				}
+				return perr
+			},
+			check: func(err error) bool {
+				var e *ParseError
+				return errors.As(err, &e) && e.Archive == "a" && e.Line == 1 && errors.Is(err, ErrTruncated)
+			},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.fn()
+			if err == nil || !c.check(err) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if errors.Is(err, os.ErrNotExist) != c.notExist {
+				t.Fatalf("unexpected os.ErrNotExist match for %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package inpx
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Exporter writes books of the index to an external storage. See Index.Export.
+type Exporter interface {
+	// ExportBook writes a single book from a given archive.
+	ExportBook(archive string, b Book) error
+	// Flush is called after all books are exported.
+	Flush() error
+}
+
+// Export calls e.ExportBook for every book of the index and then calls e.Flush.
+// Books are exported in the same order as AllBooks.
+func (idx *Index) Export(e Exporter) error {
+	if err := idx.LoadAll(); err != nil {
+		return err
+	}
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if err := e.ExportBook(name, b); err != nil {
+				return err
+			}
+		}
+	}
+	return e.Flush()
+}
+
+// JSONLinesExporter writes books as JSON objects, one per line.
+type JSONLinesExporter struct {
+	enc *json.Encoder
+}
+
+// NewJSONLinesExporter creates an exporter that writes JSON lines to w.
+func NewJSONLinesExporter(w io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{enc: json.NewEncoder(w)}
+}
+
+// ExportBook implements Exporter.
+func (e *JSONLinesExporter) ExportBook(archive string, b Book) error {
+	b.File.Archive = archive
+	return e.enc.Encode(b)
+}
+
+// Flush implements Exporter. It does nothing, since lines are written immediately.
+func (e *JSONLinesExporter) Flush() error {
+	return nil
+}
+
+// SQLiteExporter inserts books to the books table (see BooksTableSQL) of an SQL database.
+// Queries use "?" placeholders that are supported by SQLite drivers.
+// All books are inserted in a single transaction that is committed on Flush.
+type SQLiteExporter struct {
+	db   *sql.DB
+	tx   *sql.Tx
+	stmt *sql.Stmt
+}
+
+// NewSQLiteExporter creates an exporter that writes books to db. The books table is created
+// if it does not exist.
+func NewSQLiteExporter(db *sql.DB) (*SQLiteExporter, error) {
+	if _, err := db.Exec(BooksTableSQL); err != nil {
+		return nil, fmt.Errorf("error while creating books table: %v", err)
+	}
+	return &SQLiteExporter{db: db}, nil
+}
+
+const sqliteInsertBook = `INSERT INTO books (lib_id, title, authors, genres, series, series_num, deleted, date, lang, lib_rate, isbn, keywords,
+	"file.name", "file.ext", "file.dir", "file.archive", "file.size", "file.offset", "file.compressed_size", "file.method")
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+// ExportBook implements Exporter.
+func (e *SQLiteExporter) ExportBook(archive string, b Book) error {
+	if e.tx == nil {
+		tx, err := e.db.Begin()
+		if err != nil {
+			return err
+		}
+		stmt, err := tx.Prepare(sqliteInsertBook)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		e.tx, e.stmt = tx, stmt
+	}
+	var date interface{}
+	if !b.Date.IsZero() {
+		date = b.Date
+	}
+	f := b.File
+	_, err := e.stmt.Exec(b.LibId, b.Title, b.AuthorsStr(), b.GenresStr(), b.Series, b.SeriesNum, b.Deleted, date, b.Lang, b.LibRate, b.ISBN, b.KeywordsStr(),
+		f.Name, f.Ext, f.Dir, archive, f.Size, f.Offset, f.CompressedSize, int64(f.Method))
+	if err != nil {
+		e.stmt.Close()
+		e.tx.Rollback()
+		e.tx, e.stmt = nil, nil
+		return fmt.Errorf("error while inserting book %d: %v", b.LibId, err)
+	}
+	return nil
+}
+
+// Flush implements Exporter. It commits inserted books.
+func (e *SQLiteExporter) Flush() error {
+	if e.tx == nil {
+		return nil
+	}
+	e.stmt.Close()
+	err := e.tx.Commit()
+	e.tx, e.stmt = nil, nil
+	return err
+}
@@ -0,0 +1,91 @@
+package inpx
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONLinesExporter(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"b": {{LibId: 2, Title: "B"}},
+		"a": {{LibId: 1, Title: "A", Authors: []Author{newAuthor([]string{"Doe", "John"})}}},
+	}}
+	var buf bytes.Buffer
+	if err := idx.Export(NewJSONLinesExporter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"Authors":["John Doe"]`) ||
+		!strings.Contains(lines[0], `"Archive":"a"`) || !strings.Contains(lines[1], `"Title":"B"`) {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+// testDriver is an SQL driver that records executed statements.
+type testDriver struct {
+	queries []string
+	args    [][]driver.Value
+	commits int
+}
+
+func (d *testDriver) Open(name string) (driver.Conn, error) { return testConn{d}, nil }
+
+type testConn struct{ d *testDriver }
+
+func (c testConn) Prepare(query string) (driver.Stmt, error) { return testStmt{c.d, query}, nil }
+func (c testConn) Close() error                              { return nil }
+func (c testConn) Begin() (driver.Tx, error)                 { return testTx{c.d}, nil }
+
+type testTx struct{ d *testDriver }
+
+func (tx testTx) Commit() error   { tx.d.commits++; return nil }
+func (tx testTx) Rollback() error { return nil }
+
+type testStmt struct {
+	d     *testDriver
+	query string
+}
+
+func (s testStmt) Close() error  { return nil }
+func (s testStmt) NumInput() int { return -1 }
+func (s testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.queries = append(s.d.queries, s.query)
+	s.d.args = append(s.d.args, args)
+	return driver.RowsAffected(1), nil
+}
+func (s testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+
+func TestSQLiteExporter(t *testing.T) {
+	d := &testDriver{}
+	sql.Register("inpx-test", d)
+	db, err := sql.Open("inpx-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	idx := &Index{Archives: map[string][]Book{
+		"a": {{LibId: 1, Title: "A", Genres: []string{"sf", "det"}, LibRate: -1, Keywords: []string{"magic", "elves"}, File: File{Name: "1", Ext: "fb2"}}},
+	}}
+	e, err := NewSQLiteExporter(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = idx.Export(e); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.queries) != 2 || d.queries[0] != BooksTableSQL || d.commits != 1 {
+		t.Fatalf("unexpected queries: %q, %d commits", d.queries, d.commits)
+	}
+	exp := []driver.Value{int64(1), "A", "", "sf;det", "", int64(0), false, nil, "", int64(-1), "", "magic;elves",
+		"1", "fb2", "", "a", int64(0), int64(0), int64(0), int64(0)}
+	if !reflect.DeepEqual(d.args[1], exp) {
+		t.Fatalf("unexpected args: %#v", d.args[1])
+	}
+}
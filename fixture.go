@@ -0,0 +1,23 @@
+package inpx
+
+import "bytes"
+
+// MakeTestINPX generates a minimal inpx file in memory with given books. It is intended for tests.
+// The file has collection.info, version.info with version 1, and a single "books.inp" archive.
+func MakeTestINPX(books []Book) ([]byte, error) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.WriteCollectionInfo("Test collection", ""); err != nil {
+		return nil, err
+	}
+	if err := w.WriteVersion("1"); err != nil {
+		return nil, err
+	}
+	if err := w.WriteArchive("books", books); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
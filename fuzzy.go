@@ -0,0 +1,171 @@
+package inpx
+
+import (
+	"sort"
+	"strings"
+)
+
+// SearchResult is a book found by a fuzzy search.
+type SearchResult struct {
+	Book  Book
+	Score float64 // similarity of the title to the query, from 0 to 1
+}
+
+// trigrams returns a set of unique trigrams of normalized text. Text is padded with spaces,
+// so short words and word boundaries produce trigrams as well.
+func trigrams(s string) map[string]struct{} {
+	r := []rune("  " + normalizeText(s) + " ")
+	out := make(map[string]struct{}, len(r))
+	for i := 0; i+3 <= len(r); i++ {
+		out[string(r[i:i+3])] = struct{}{}
+	}
+	return out
+}
+
+// TitleIndex is a trigram index of book titles for repeated fuzzy searches.
+type TitleIndex struct {
+	books  []Book
+	counts []int            // number of unique trigrams of each title
+	posts  map[string][]int // books that contain each trigram
+}
+
+// BuildTitleIndex builds a trigram index of all book titles.
+func (idx *Index) BuildTitleIndex() *TitleIndex {
+	ti := &TitleIndex{
+		books: idx.AllBooks(),
+		posts: make(map[string][]int),
+	}
+	ti.counts = make([]int, len(ti.books))
+	for i, b := range ti.books {
+		tri := trigrams(b.Title)
+		ti.counts[i] = len(tri)
+		for t := range tri {
+			ti.posts[t] = append(ti.posts[t], i)
+		}
+	}
+	return ti
+}
+
+// Search returns books with titles similar to the query. Similarity is a Jaccard index
+// of title and query trigrams. Only books with a positive score that is at least threshold
+// are returned. Results are sorted by score in descending order; books with the same score
+// are returned in the same order as Index.AllBooks.
+func (ti *TitleIndex) Search(query string, threshold float64) []SearchResult {
+	qtri := trigrams(query)
+	common := make(map[int]int)
+	for t := range qtri {
+		for _, i := range ti.posts[t] {
+			common[i]++
+		}
+	}
+	ids := make([]int, 0, len(common))
+	for i := range common {
+		ids = append(ids, i)
+	}
+	sort.Ints(ids)
+	var out []SearchResult
+	for _, i := range ids {
+		n := common[i]
+		score := float64(n) / float64(len(qtri)+ti.counts[i]-n)
+		if score >= threshold {
+			out = append(out, SearchResult{Book: ti.books[i], Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Score > out[j].Score
+	})
+	return out
+}
+
+// FuzzySearch is like TitleIndex.Search, but builds the title index on each call.
+// Use BuildTitleIndex for repeated queries.
+func (idx *Index) FuzzySearch(query string, threshold float64) []SearchResult {
+	return idx.BuildTitleIndex().Search(query, threshold)
+}
+
+// trigramSimilarity returns a Jaccard index of trigrams of two strings.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	n := 0
+	for t := range ta {
+		if _, ok := tb[t]; ok {
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return float64(n) / float64(len(ta)+len(tb)-n)
+}
+
+// Score returns how well the query matches the author, from 0 to 1. The query is compared
+// case-insensitively with each name part, and the best score is returned: 1 for an exact match,
+// 0.8 for a prefix, 0.5 for a substring and 0.3 if trigram similarity is above 0.3.
+func (a Author) Score(query string) float64 {
+	q := normalizeText(query)
+	if q == "" {
+		return 0
+	}
+	best := 0.0
+	for _, p := range []string{a.LastName, a.FirstName, a.MiddleName} {
+		p = normalizeText(p)
+		if p == "" {
+			continue
+		}
+		var s float64
+		switch {
+		case p == q:
+			s = 1
+		case strings.HasPrefix(p, q):
+			s = 0.8
+		case strings.Contains(p, q):
+			s = 0.5
+		case trigramSimilarity(p, q) > 0.3:
+			s = 0.3
+		}
+		if s > best {
+			best = s
+		}
+	}
+	return best
+}
+
+// AuthorSearchResult is an author found by Index.SearchByAuthor.
+type AuthorSearchResult struct {
+	Author Author
+	Score  float64 // see Author.Score
+	Books  []Book  // books of the author, in the same order as Index.AllBooks
+}
+
+// SearchByAuthor returns authors which score for the query is positive and at least minScore
+// (see Author.Score). Authors are matched with Author.Equals. Results are sorted by score
+// in descending order, and then by author name (see Author.String).
+func (idx *Index) SearchByAuthor(query string, minScore float64) []AuthorSearchResult {
+	var out []AuthorSearchResult
+	byKey := make(map[string]int)
+	for _, b := range idx.AllBooks() {
+		for _, a := range b.Authors {
+			k := a.key()
+			if i, ok := byKey[k]; ok {
+				if i >= 0 {
+					out[i].Books = append(out[i].Books, b)
+				}
+				continue
+			}
+			s := a.Score(query)
+			if s == 0 || s < minScore {
+				byKey[k] = -1
+				continue
+			}
+			byKey[k] = len(out)
+			out = append(out, AuthorSearchResult{Author: a, Score: s, Books: []Book{b}})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Author.String() < out[j].Author.String()
+	})
+	return out
+}
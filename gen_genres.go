@@ -0,0 +1,48 @@
+//go:build ignore
+
+// This program generates genres.go from genres.csv. Run it with go generate.
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+)
+
+func main() {
+	f, err := os.Open("genres.csv")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_genres.go; DO NOT EDIT.\n\npackage inpx\n\n")
+	buf.WriteString("// GenreNames maps genre codes to human-readable names.\n")
+	buf.WriteString("var GenreNames = map[string]string{\n")
+	for _, row := range rows[1:] {
+		fmt.Fprintf(&buf, "\t%q: %q,\n", row[0], row[2])
+	}
+	buf.WriteString("}\n\n")
+	buf.WriteString("// GenreHierarchy maps genre codes to codes of their parent genres.\n")
+	buf.WriteString("var GenreHierarchy = map[string]string{\n")
+	for _, row := range rows[1:] {
+		if row[1] != "" {
+			fmt.Fprintf(&buf, "\t%q: %q,\n", row[0], row[1])
+		}
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile("genres.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
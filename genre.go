@@ -0,0 +1,112 @@
+package inpx
+
+import (
+	"sort"
+	"strings"
+)
+
+//go:generate go run gen_genres.go
+
+// GenreName returns a human-readable name of the genre. The lookup is case-insensitive.
+// If the genre is unknown, the code itself is returned.
+func GenreName(code string) string {
+	if name, ok := GenreNames[strings.ToLower(strings.TrimSpace(code))]; ok {
+		return name
+	}
+	return code
+}
+
+// GenreCodes returns all known genre codes in sorted order.
+func GenreCodes() []string {
+	codes := make([]string, 0, len(GenreNames))
+	for code := range GenreNames {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// BooksByGenre groups books of the index by lowercase genre code. Books with multiple genres
+// are listed under each of them. Deleted books are skipped.
+func (idx *Index) BooksByGenre() map[string][]Book {
+	out := make(map[string][]Book)
+	for _, b := range idx.AllBooks() {
+		if b.Deleted {
+			continue
+		}
+		for _, g := range b.Genres {
+			if g = strings.ToLower(strings.TrimSpace(g)); g != "" {
+				out[g] = append(out[g], b)
+			}
+		}
+	}
+	return out
+}
+
+// GenreDistribution returns the number of books for each lowercase genre code.
+// Deleted books are skipped.
+func (idx *Index) GenreDistribution() map[string]int {
+	out := make(map[string]int)
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.Deleted {
+				continue
+			}
+			for _, g := range b.Genres {
+				if g = strings.ToLower(strings.TrimSpace(g)); g != "" {
+					out[g]++
+				}
+			}
+		}
+	}
+	return out
+}
+
+// GenreAncestors returns a chain of genres from the given code up to the root genre,
+// including the code itself. Codes are lowercase.
+func GenreAncestors(code string) []string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return nil
+	}
+	chain := []string{code}
+	seen := map[string]bool{code: true}
+	for {
+		parent, ok := GenreHierarchy[code]
+		if !ok || seen[parent] {
+			return chain
+		}
+		chain = append(chain, parent)
+		seen[parent] = true
+		code = parent
+	}
+}
+
+// hasAncestor checks if the genre is code or one of its subgenres.
+func hasAncestor(genre, code string) bool {
+	for _, g := range GenreAncestors(genre) {
+		if g == code {
+			return true
+		}
+	}
+	return false
+}
+
+// BooksByGenreRecursive returns books tagged with the genre or with any of its subgenres
+// (see GenreHierarchy). Deleted books are skipped.
+func (idx *Index) BooksByGenreRecursive(code string) []Book {
+	code = strings.ToLower(strings.TrimSpace(code))
+	var out []Book
+	for _, b := range idx.AllBooks() {
+		if b.Deleted {
+			continue
+		}
+		for _, g := range b.Genres {
+			if hasAncestor(g, code) {
+				out = append(out, b)
+				break
+			}
+		}
+	}
+	return out
+}
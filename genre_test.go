@@ -0,0 +1,53 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBooksByGenre(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Genres: []string{"sf", "SF_Space"}},
+			{LibId: 2, Genres: []string{"sf"}},
+			{LibId: 3, Genres: []string{"sf"}, Deleted: true},
+		},
+	}}
+	byGenre := idx.BooksByGenre()
+	if len(byGenre["sf"]) != 2 || len(byGenre["sf_space"]) != 1 {
+		t.Fatalf("unexpected genres: %v", byGenre)
+	}
+	dist := idx.GenreDistribution()
+	if len(dist) != 2 || dist["sf"] != 2 || dist["sf_space"] != 1 {
+		t.Fatalf("unexpected distribution: %v", dist)
+	}
+	if name := GenreName("SF_SPACE"); name != "Space science fiction" {
+		t.Fatalf("unexpected genre name: %q", name)
+	}
+	if name := GenreName("unknown"); name != "unknown" {
+		t.Fatalf("unexpected genre name: %q", name)
+	}
+}
+
+func TestGenreHierarchy(t *testing.T) {
+	if got := GenreAncestors("SF_Space"); !reflect.DeepEqual(got, []string{"sf_space", "sf"}) {
+		t.Fatalf("unexpected ancestors: %v", got)
+	}
+	if got := GenreAncestors("unknown"); !reflect.DeepEqual(got, []string{"unknown"}) {
+		t.Fatalf("unexpected ancestors: %v", got)
+	}
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Genres: []string{"sf_space", "sf_action"}},
+			{LibId: 2, Genres: []string{"sf"}},
+			{LibId: 3, Genres: []string{"det_action"}},
+		},
+	}}
+	books := idx.BooksByGenreRecursive("sf")
+	if len(books) != 2 || books[0].LibId != 1 || books[1].LibId != 2 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	if books = idx.BooksByGenreRecursive("detective"); len(books) != 1 || books[0].LibId != 3 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+}
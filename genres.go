@@ -0,0 +1,251 @@
+// Code generated by gen_genres.go; DO NOT EDIT.
+
+package inpx
+
+// GenreNames maps genre codes to human-readable names.
+var GenreNames = map[string]string{
+	"sf_history":         "Alternative history",
+	"sf_action":          "Action science fiction",
+	"sf_epic":            "Epic science fiction",
+	"sf_heroic":          "Heroic fantasy",
+	"sf_detective":       "Detective science fiction",
+	"sf_cyberpunk":       "Cyberpunk",
+	"sf_space":           "Space science fiction",
+	"sf_social":          "Social science fiction",
+	"sf_horror":          "Horror & mystic",
+	"sf_humor":           "Humor science fiction",
+	"sf_fantasy":         "Fantasy",
+	"sf_fantasy_city":    "City fantasy",
+	"sf_mystic":          "Mystic",
+	"sf_postapocalyptic": "Post-apocalyptic",
+	"sf_stimpank":        "Steampunk",
+	"sf_technofantasy":   "Technofantasy",
+	"sf_etc":             "Other science fiction",
+	"sf":                 "Science fiction",
+	"hronoopera":         "Chrono-opera",
+	"popadancy":          "Time travel",
+	"det_classic":        "Classical detective",
+	"det_police":         "Police detective",
+	"det_action":         "Action",
+	"det_irony":          "Ironical detective",
+	"det_history":        "Historical detective",
+	"det_espionage":      "Espionage detective",
+	"det_crime":          "Crime detective",
+	"det_political":      "Political detective",
+	"det_maniac":         "Maniacs",
+	"det_hard":           "Hard-boiled",
+	"thriller":           "Thriller",
+	"detective":          "Detective",
+	"prose_classic":      "Classical prose",
+	"prose_history":      "Historical prose",
+	"prose_contemporary": "Contemporary prose",
+	"prose_counter":      "Counterculture",
+	"prose_rus_classic":  "Russian classics",
+	"prose_su_classics":  "Soviet classics",
+	"prose_military":     "Military prose",
+	"prose":              "Prose",
+	"love_contemporary":  "Contemporary romance",
+	"love_history":       "Historical romance",
+	"love_detective":     "Detective romance",
+	"love_short":         "Short romance",
+	"love_erotica":       "Erotica",
+	"love_sf":            "Romantic fantasy",
+	"love":               "Romance",
+	"adv_western":        "Western",
+	"adv_history":        "Historical adventure",
+	"adv_indian":         "Indians",
+	"adv_maritime":       "Maritime fiction",
+	"adv_geo":            "Travel & geography",
+	"adv_animal":         "Nature & animals",
+	"adventure":          "Adventure",
+	"child_tale":         "Fairy tales",
+	"child_verse":        "Children's verses",
+	"child_prose":        "Children's prose",
+	"child_sf":           "Children's science fiction",
+	"child_det":          "Children's detective",
+	"child_adv":          "Children's adventure",
+	"child_education":    "Children's education",
+	"children":           "Children's literature",
+	"poetry":             "Poetry",
+	"dramaturgy":         "Dramaturgy",
+	"antique_ant":        "Classical antiquity",
+	"antique_european":   "European literature",
+	"antique_russian":    "Old Russian literature",
+	"antique_east":       "Old East literature",
+	"antique_myths":      "Myths, legends and epos",
+	"antique":            "Antique literature",
+	"sci_history":        "History",
+	"sci_psychology":     "Psychology",
+	"sci_culture":        "Cultural science",
+	"sci_religion":       "Religious studies",
+	"sci_philosophy":     "Philosophy",
+	"sci_politics":       "Politics",
+	"sci_business":       "Business literature",
+	"sci_economy":        "Economics",
+	"sci_juris":          "Jurisprudence",
+	"sci_linguistic":     "Linguistics",
+	"sci_medicine":       "Medicine",
+	"sci_phys":           "Physics",
+	"sci_math":           "Mathematics",
+	"sci_chem":           "Chemistry",
+	"sci_biology":        "Biology",
+	"sci_tech":           "Technical science",
+	"sci_radio":          "Radio electronics",
+	"sci_build":          "Construction",
+	"sci_transport":      "Transport & aviation",
+	"science":            "Science",
+	"comp_www":           "Internet",
+	"comp_programming":   "Programming",
+	"comp_hard":          "Computer hardware",
+	"comp_soft":          "Software",
+	"comp_db":            "Databases",
+	"comp_osnet":         "OS & networking",
+	"computers":          "Computers",
+	"ref_encyc":          "Encyclopedias",
+	"ref_dict":           "Dictionaries",
+	"ref_ref":            "Reference",
+	"ref_guide":          "Guidebooks",
+	"reference":          "Reference literature",
+	"nonf_biography":     "Biography & memoirs",
+	"nonf_publicism":     "Publicism",
+	"nonf_criticism":     "Criticism",
+	"nonf_military":      "Military documentary",
+	"design":             "Art & design",
+	"nonfiction":         "Nonfiction",
+	"religion_rel":       "Religion",
+	"religion_esoterics": "Esoterics",
+	"religion_self":      "Self-improvement",
+	"religion":           "Religion & spirituality",
+	"humor_anecdote":     "Anecdotes",
+	"humor_prose":        "Humorous prose",
+	"humor_verse":        "Humorous verses",
+	"humor":              "Humor",
+	"home_cooking":       "Cooking",
+	"home_pets":          "Pets",
+	"home_crafts":        "Hobbies & crafts",
+	"home_entertain":     "Entertaining",
+	"home_health":        "Health",
+	"home_garden":        "Garden",
+	"home_diy":           "Do it yourself",
+	"home_sport":         "Sports",
+	"home_sex":           "Erotica & sex",
+	"home":               "Home & family",
+	"military_weapon":    "Weapons",
+	"military_special":   "Military special",
+}
+
+// GenreHierarchy maps genre codes to codes of their parent genres.
+var GenreHierarchy = map[string]string{
+	"sf_history":         "sf",
+	"sf_action":          "sf",
+	"sf_epic":            "sf",
+	"sf_heroic":          "sf",
+	"sf_detective":       "sf",
+	"sf_cyberpunk":       "sf",
+	"sf_space":           "sf",
+	"sf_social":          "sf",
+	"sf_horror":          "sf",
+	"sf_humor":           "sf",
+	"sf_fantasy":         "sf",
+	"sf_fantasy_city":    "sf",
+	"sf_mystic":          "sf",
+	"sf_postapocalyptic": "sf",
+	"sf_stimpank":        "sf",
+	"sf_technofantasy":   "sf",
+	"sf_etc":             "sf",
+	"hronoopera":         "sf",
+	"popadancy":          "sf",
+	"det_classic":        "detective",
+	"det_police":         "detective",
+	"det_action":         "detective",
+	"det_irony":          "detective",
+	"det_history":        "detective",
+	"det_espionage":      "detective",
+	"det_crime":          "detective",
+	"det_political":      "detective",
+	"det_maniac":         "detective",
+	"det_hard":           "detective",
+	"thriller":           "detective",
+	"prose_classic":      "prose",
+	"prose_history":      "prose",
+	"prose_contemporary": "prose",
+	"prose_counter":      "prose",
+	"prose_rus_classic":  "prose",
+	"prose_su_classics":  "prose",
+	"prose_military":     "prose",
+	"love_contemporary":  "love",
+	"love_history":       "love",
+	"love_detective":     "love",
+	"love_short":         "love",
+	"love_erotica":       "love",
+	"love_sf":            "love",
+	"adv_western":        "adventure",
+	"adv_history":        "adventure",
+	"adv_indian":         "adventure",
+	"adv_maritime":       "adventure",
+	"adv_geo":            "adventure",
+	"adv_animal":         "adventure",
+	"child_tale":         "children",
+	"child_verse":        "children",
+	"child_prose":        "children",
+	"child_sf":           "children",
+	"child_det":          "children",
+	"child_adv":          "children",
+	"child_education":    "children",
+	"antique_ant":        "antique",
+	"antique_european":   "antique",
+	"antique_russian":    "antique",
+	"antique_east":       "antique",
+	"antique_myths":      "antique",
+	"sci_history":        "science",
+	"sci_psychology":     "science",
+	"sci_culture":        "science",
+	"sci_religion":       "science",
+	"sci_philosophy":     "science",
+	"sci_politics":       "science",
+	"sci_business":       "science",
+	"sci_economy":        "science",
+	"sci_juris":          "science",
+	"sci_linguistic":     "science",
+	"sci_medicine":       "science",
+	"sci_phys":           "science",
+	"sci_math":           "science",
+	"sci_chem":           "science",
+	"sci_biology":        "science",
+	"sci_tech":           "science",
+	"sci_radio":          "science",
+	"sci_build":          "science",
+	"sci_transport":      "science",
+	"comp_www":           "computers",
+	"comp_programming":   "computers",
+	"comp_hard":          "computers",
+	"comp_soft":          "computers",
+	"comp_db":            "computers",
+	"comp_osnet":         "computers",
+	"ref_encyc":          "reference",
+	"ref_dict":           "reference",
+	"ref_ref":            "reference",
+	"ref_guide":          "reference",
+	"nonf_biography":     "nonfiction",
+	"nonf_publicism":     "nonfiction",
+	"nonf_criticism":     "nonfiction",
+	"nonf_military":      "nonfiction",
+	"design":             "nonfiction",
+	"religion_rel":       "religion",
+	"religion_esoterics": "religion",
+	"religion_self":      "religion",
+	"humor_anecdote":     "humor",
+	"humor_prose":        "humor",
+	"humor_verse":        "humor",
+	"home_cooking":       "home",
+	"home_pets":          "home",
+	"home_crafts":        "home",
+	"home_entertain":     "home",
+	"home_health":        "home",
+	"home_garden":        "home",
+	"home_diy":           "home",
+	"home_sport":         "home",
+	"home_sex":           "home",
+	"military_weapon":    "nonfiction",
+	"military_special":   "nonfiction",
+}
@@ -0,0 +1,43 @@
+package inpx
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ContentTypes maps book file extensions to MIME types returned by Book.ContentType.
+var ContentTypes = map[string]string{
+	"fb2":  "application/x-fictionbook+xml",
+	"epub": "application/epub+zip",
+	"pdf":  "application/pdf",
+	"djvu": "image/vnd.djvu",
+	"mobi": "application/x-mobipocket-ebook",
+	"azw3": "application/vnd.amazon.ebook",
+	"txt":  "text/plain",
+	"rtf":  "application/rtf",
+	"doc":  "application/msword",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"html": "text/html",
+	"zip":  "application/zip",
+}
+
+// ContentType returns a MIME type of the book file based on its extension (see ContentTypes).
+// It returns "application/octet-stream" for unknown extensions.
+func (b Book) ContentType() string {
+	if t, ok := ContentTypes[strings.ToLower(b.File.Ext)]; ok {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// CoverURL returns a URL of the book cover in baseURL/cover/libid form.
+func (b Book) CoverURL(baseURL string) string {
+	return baseURL + "/cover/" + strconv.Itoa(b.LibId)
+}
+
+// DownloadURL returns a URL of the book file in baseURL/download/archive/name.ext form.
+// Archive and file names are escaped.
+func (b Book) DownloadURL(baseURL string) string {
+	return baseURL + "/download/" + url.PathEscape(b.File.Archive) + "/" + url.PathEscape(b.File.zipName())
+}
@@ -0,0 +1,20 @@
+package inpx
+
+import "testing"
+
+func TestBookURLs(t *testing.T) {
+	b := Book{LibId: 42, File: File{Name: "42", Ext: "EPUB", Archive: "fb2-001 new"}}
+	if got := b.CoverURL("http://example.com"); got != "http://example.com/cover/42" {
+		t.Fatalf("unexpected cover URL: %q", got)
+	}
+	if got := b.DownloadURL("http://example.com"); got != "http://example.com/download/fb2-001%20new/42.EPUB" {
+		t.Fatalf("unexpected download URL: %q", got)
+	}
+	if got := b.ContentType(); got != "application/epub+zip" {
+		t.Fatalf("unexpected content type: %q", got)
+	}
+	b.File.Ext = "xyz"
+	if got := b.ContentType(); got != "application/octet-stream" {
+		t.Fatalf("unexpected content type: %q", got)
+	}
+}
@@ -0,0 +1,304 @@
+package inpx
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// ImportExts is a set of book file extensions that are picked up by Writer.AddFromDirectory.
+var ImportExts = map[string]bool{
+	"fb2":  true,
+	"epub": true,
+	"pdf":  true,
+	"djvu": true,
+	"mobi": true,
+	"azw3": true,
+	"txt":  true,
+	"rtf":  true,
+	"doc":  true,
+}
+
+// fb2TitleInfo is a title-info section of fb2 file description.
+type fb2TitleInfo struct {
+	Genres  []string `xml:"genre"`
+	Authors []struct {
+		First  string `xml:"first-name"`
+		Middle string `xml:"middle-name"`
+		Last   string `xml:"last-name"`
+	} `xml:"author"`
+	Title    string `xml:"book-title"`
+	Lang     string `xml:"lang"`
+	Sequence *struct {
+		Name   string `xml:"name,attr"`
+		Number string `xml:"number,attr"`
+	} `xml:"sequence"`
+}
+
+// readFB2Info reads book metadata from the title-info section of fb2 file.
+func readFB2Info(r io.Reader, b *Book) error {
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = func(label string, r io.Reader) (io.Reader, error) {
+		enc, err := htmlindex.Get(label)
+		if err != nil {
+			return nil, err
+		}
+		return enc.NewDecoder().Reader(r), nil
+	}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok || se.Name.Local != "title-info" {
+			continue
+		}
+		var info fb2TitleInfo
+		if err = dec.DecodeElement(&info, &se); err != nil {
+			return err
+		}
+		for _, a := range info.Authors {
+			parts := []string{a.Last, a.First, a.Middle}
+			for len(parts) > 1 && strings.TrimSpace(parts[len(parts)-1]) == "" {
+				parts = parts[:len(parts)-1]
+			}
+			b.Authors = append(b.Authors, newAuthor(parts))
+		}
+		for _, g := range info.Genres {
+			if g = strings.TrimSpace(g); g != "" {
+				b.Genres = append(b.Genres, g)
+			}
+		}
+		b.Title = strings.TrimSpace(info.Title)
+		b.Lang = strings.TrimSpace(info.Lang)
+		if s := info.Sequence; s != nil {
+			b.Series = strings.TrimSpace(s.Name)
+			b.SeriesNum, _ = strconv.Atoi(strings.TrimSpace(s.Number))
+		}
+		return nil
+	}
+}
+
+// bookFromFileName infers book metadata from a file name without the extension.
+// Names are expected to be in "LastName_FirstName_Title" form; "LastName_Title" and "Title"
+// are accepted as well.
+func bookFromFileName(name string, b *Book) {
+	parts := strings.Split(name, "_")
+	switch {
+	case len(parts) >= 3:
+		b.Authors = []Author{newAuthor([]string{parts[0], parts[1]})}
+		b.Title = strings.Join(parts[2:], " ")
+	case len(parts) == 2:
+		b.Authors = []Author{newAuthor([]string{parts[0]})}
+		b.Title = parts[1]
+	default:
+		b.Title = name
+	}
+}
+
+// bookFromFile creates a book record for a file in the directory.
+func bookFromFile(dir string, fi os.FileInfo) (Book, error) {
+	ext := filepath.Ext(fi.Name())
+	b := Book{
+		File: File{
+			Name: strings.TrimSuffix(fi.Name(), ext),
+			Ext:  strings.ToLower(strings.TrimPrefix(ext, ".")),
+			Size: int(fi.Size()),
+		},
+		Date:    fi.ModTime(),
+		LibRate: -1,
+	}
+	b.LibId, _ = strconv.Atoi(b.File.Name)
+	if b.File.Ext == "fb2" {
+		f, err := os.Open(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			return Book{}, err
+		}
+		err = readFB2Info(f, &b)
+		f.Close()
+		if err == nil && b.Title != "" {
+			return b, nil
+		}
+		b.Authors, b.Genres = nil, nil
+	}
+	bookFromFileName(b.File.Name, &b)
+	return b, nil
+}
+
+// AddFromDirectory writes an inp file for a given archive with records for all book files
+// in the directory (see ImportExts). Subdirectories are not scanned. Metadata is read from
+// the title-info of fb2 files; for other formats (or if the fb2 header cannot be read)
+// it is inferred from the file name in "LastName_FirstName_Title" form. Numeric file names
+// are used as LibId.
+// If structure is nil, the structure of the writer is used.
+func (w *Writer) AddFromDirectory(dir string, archive string, structure []int) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var books []Book
+	for _, e := range entries {
+		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(e.Name()), "."))
+		if e.IsDir() || !ImportExts[ext] {
+			continue
+		}
+		fi, err := e.Info()
+		if err != nil {
+			return err
+		}
+		b, err := bookFromFile(dir, fi)
+		if err != nil {
+			return fmt.Errorf("error while reading %s: %v", e.Name(), err)
+		}
+		b.File.Archive = archive
+		books = append(books, b)
+	}
+	if structure == nil {
+		structure = w.c.structure
+	}
+	return w.writeArchive(archive, books, structure)
+}
+
+// maxLibId returns the maximal LibId of all books in the index.
+func (idx *Index) maxLibId() int {
+	max := 0
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.LibId > max {
+				max = b.LibId
+			}
+		}
+	}
+	return max
+}
+
+// copyArchive copies all files of the archive zip to zw. It fails if the archive already has
+// a file with a given name. Archives that do not exist are skipped.
+func copyArchive(zw *zip.Writer, path, name string) error {
+	zf, err := zip.OpenReader(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer zf.Close()
+	for _, f := range zf.File {
+		if f.Name == name {
+			return fmt.Errorf("file %s already exists in %s", name, path)
+		}
+		if err = zw.Copy(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteArchive copies the archive zip to a temporary file and calls fn to add a file
+// with a given name to it. The archive is created if it does not exist.
+// The temporary file replaces the original archive on success.
+func rewriteArchive(path, name string, fn func(zw *zip.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	zw := zip.NewWriter(tmp)
+	if err = copyArchive(zw, path, name); err != nil {
+		return err
+	}
+	if err = fn(zw); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// addToArchive adds a file to the archive zip, creating the archive if it does not exist.
+func addToArchive(path, name string, data []byte) error {
+	return rewriteArchive(path, name, func(zw *zip.Writer) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+		if err != nil {
+			return err
+		}
+		_, err = fw.Write(data)
+		return err
+	})
+}
+
+// CopyBook copies the book file to another archive zip without decompressing it
+// (see File.OpenCompressed). The archive is created if it does not exist.
+// It fails if the archive already has a file with the same name, and returns ErrSplitFile
+// if the file is split across several archives.
+func CopyBook(src File, destArchivePath string) error {
+	hdr, rc, err := src.openRaw()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return rewriteArchive(destArchivePath, hdr.Name, func(zw *zip.Writer) error {
+		fw, err := zw.CreateRaw(hdr)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, rc)
+		return err
+	})
+}
+
+// ImportBookFile adds a book file to the archive zip and adds the book to the index.
+// The archive is created in meta.File.Dir, or in the directory of existing books of the archive
+// if it is not set. The file is stored as meta.File.Name + "." + meta.File.Ext; if they are not set,
+// the new LibId and the extension of filePath are used. The book gets a new LibId that is
+// greater than LibIds of all books in the index; File.Size and File.Archive are set as well.
+func (idx *Index) ImportBookFile(filePath, archiveName string, meta Book) error {
+	if err := idx.LoadAll(); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	b := meta.Clone()
+	b.LibId = idx.maxLibId() + 1
+	fr := &b.File
+	if fr.Dir == "" && len(idx.Archives[archiveName]) != 0 {
+		fr.Dir = idx.Archives[archiveName][0].File.Dir
+	}
+	if fr.Ext == "" {
+		fr.Ext = strings.TrimPrefix(filepath.Ext(filePath), ".")
+	}
+	if fr.Name == "" {
+		fr.Name = strconv.Itoa(b.LibId)
+	}
+	fr.Archive = archiveName
+	fr.Size = len(data)
+	fr.Offset, fr.CompressedSize, fr.Method = 0, 0, 0
+	if err = addToArchive(fr.AbsolutePath(), fr.zipName(), data); err != nil {
+		return fmt.Errorf("error while writing archive: %v", err)
+	}
+	if idx.Archives == nil {
+		idx.Archives = make(map[string][]Book)
+	}
+	idx.Archives[archiveName] = append(idx.Archives[archiveName], b)
+	if idx.ArchiveFiles != nil {
+		if fi, err := os.Stat(fr.AbsolutePath()); err == nil {
+			idx.ArchiveFiles[archiveName] = ArchiveFile{ZipSize: fi.Size(), ModTime: fi.ModTime()}
+		}
+	}
+	return nil
+}
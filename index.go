@@ -0,0 +1,434 @@
+package inpx
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// archiveNames returns archive names of the index in lexicographic order.
+func (idx *Index) archiveNames() []string {
+	names := make([]string, 0, len(idx.Archives))
+	for name := range idx.Archives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TotalBooks returns the number of books in all archives.
+func (idx *Index) TotalBooks() int {
+	n := 0
+	for _, books := range idx.Archives {
+		n += len(books)
+	}
+	return n
+}
+
+// AllBooks returns all books of the index. Books are sorted by archive name
+// and keep their original order within each archive.
+func (idx *Index) AllBooks() []Book {
+	out := make([]Book, 0, idx.TotalBooks())
+	for _, name := range idx.archiveNames() {
+		out = append(out, idx.Archives[name]...)
+	}
+	return out
+}
+
+// Filter returns all books for which fn returns true, in the same order as AllBooks.
+func (idx *Index) Filter(fn func(Book) bool) []Book {
+	var out []Book
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if fn(b) {
+				out = append(out, b)
+			}
+		}
+	}
+	return out
+}
+
+// FilterWithFiles returns all books that have an associated file (see Book.HasFile).
+func (idx *Index) FilterWithFiles() []Book {
+	return idx.Filter(Book.HasFile)
+}
+
+// FilterWithoutFiles returns all metadata-only books that have no associated file (see Book.HasFile).
+func (idx *Index) FilterWithoutFiles() []Book {
+	return idx.Filter(func(b Book) bool {
+		return !b.HasFile()
+	})
+}
+
+// FilterByDateRange returns books with dates in the [from, to] range, sorted by date.
+// Zero from or to means that the range is unbounded on that side.
+func (idx *Index) FilterByDateRange(from, to time.Time) []Book {
+	var out []Book
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if !from.IsZero() && b.Date.Before(from) {
+				continue
+			}
+			if !to.IsZero() && b.Date.After(to) {
+				continue
+			}
+			out = append(out, b)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Date.Before(out[j].Date)
+	})
+	return out
+}
+
+// FilterByLibRate returns books rated at least min (see Book.LibRate), in the same order as AllBooks.
+// Books that are not rated are only returned if min is negative.
+func (idx *Index) FilterByLibRate(min int) []Book {
+	return idx.Filter(func(b Book) bool {
+		return b.LibRate >= min
+	})
+}
+
+// Age returns the time passed since the book was added to the library.
+func (b Book) Age() time.Duration {
+	return time.Since(b.Date)
+}
+
+// byDateDesc sorts books from the newest to the oldest.
+type byDateDesc []Book
+
+func (s byDateDesc) Len() int           { return len(s) }
+func (s byDateDesc) Less(i, j int) bool { return s[i].Date.After(s[j].Date) }
+func (s byDateDesc) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// NewerThan returns books that were added less than d ago (see Book.Age), sorted from the newest one.
+func (idx *Index) NewerThan(d time.Duration) []Book {
+	since := time.Now().Add(-d)
+	n := 0
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.Date.After(since) {
+				n++
+			}
+		}
+	}
+	out := make([]Book, 0, n)
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if b.Date.After(since) {
+				out = append(out, b)
+			}
+		}
+	}
+	sort.Stable(byDateDesc(out))
+	return out
+}
+
+// Compact removes all deleted books from the index and returns the number of removed records.
+// Archives that have no books left are removed from the index. Book slices of changed archives
+// are replaced with new ones, so slices returned earlier (e.g. by Archive) are not modified.
+func (idx *Index) Compact() int {
+	removed := 0
+	for name, books := range idx.Archives {
+		n := 0
+		for _, b := range books {
+			if !b.Deleted {
+				n++
+			}
+		}
+		if n == 0 {
+			removed += len(books)
+			delete(idx.Archives, name)
+			continue
+		} else if n == len(books) {
+			continue
+		}
+		removed += len(books) - n
+		out := make([]Book, 0, n)
+		for _, b := range books {
+			if !b.Deleted {
+				out = append(out, b)
+			}
+		}
+		idx.Archives[name] = out
+	}
+	return removed
+}
+
+// DuplicateKey returns a key that is used to match duplicate books.
+type DuplicateKey func(Book) string
+
+func normalizeText(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// TitleAuthorLangKey is a default DuplicateKey. It matches books by normalized title,
+// first author and language.
+func TitleAuthorLangKey(b Book) string {
+	author := ""
+	if len(b.Authors) != 0 {
+		author = b.Authors[0].FullName()
+	}
+	return normalizeText(b.Title) + "\x00" + normalizeText(author) + "\x00" + normalizeText(b.Lang)
+}
+
+// Fingerprint returns a stable hash of the book content that is based on normalized title,
+// last name of the first author, language and file extension. It can be used as DuplicateKey:
+//
+//	idx.FindDuplicatesBy(Book.Fingerprint)
+//
+// Fingerprint is not a cryptographic checksum of the book file.
+func (b Book) Fingerprint() string {
+	author := ""
+	if len(b.Authors) != 0 {
+		author = b.Authors[0].LastName
+	}
+	h := sha256.New()
+	for _, s := range []string{
+		normalizeText(b.Title), normalizeText(author),
+		b.LangNormalized(), strings.ToLower(b.File.Ext),
+	} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// FindDuplicates is like FindDuplicatesBy, but uses TitleAuthorLangKey to match books.
+func (idx *Index) FindDuplicates() [][]Book {
+	return idx.FindDuplicatesBy(TitleAuthorLangKey)
+}
+
+// FindDuplicatesBy groups books with the same key across all archives and returns
+// every group that has more than one book. Books in each group are sorted by date,
+// so the oldest copy goes first.
+func (idx *Index) FindDuplicatesBy(key DuplicateKey) [][]Book {
+	var keys []string
+	groups := make(map[string][]Book)
+	for _, b := range idx.AllBooks() {
+		k := key(b)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], b)
+	}
+	var out [][]Book
+	for _, k := range keys {
+		g := groups[k]
+		if len(g) < 2 {
+			continue
+		}
+		sort.SliceStable(g, func(i, j int) bool {
+			return g[i].Date.Before(g[j].Date)
+		})
+		out = append(out, g)
+	}
+	return out
+}
+
+// RateDistribution returns the number of books with each library rating from 0 to 5.
+// Deleted books are skipped. Unrated books (LibRate is -1) are counted as rated 0,
+// and ratings above 5 are counted as 5.
+func (idx *Index) RateDistribution() [6]int {
+	var out [6]int
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.Deleted {
+				continue
+			}
+			r := b.LibRate
+			if r < 0 {
+				r = 0
+			} else if r > 5 {
+				r = 5
+			}
+			out[r]++
+		}
+	}
+	return out
+}
+
+// ArchivesContaining returns names of all archives that have a record with a given LibId.
+// Names are returned in natural order (see NaturalLess).
+func (idx *Index) ArchivesContaining(libId int) []string {
+	var out []string
+	for _, name := range idx.SortedArchiveNames() {
+		for _, b := range idx.Archives[name] {
+			if b.LibId == libId {
+				out = append(out, name)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// BuildLibIdIndex returns a map from LibId to the book for fast lookups. If several books
+// have the same LibId, the first one in AllBooks order is used. Books without LibId are skipped.
+func (idx *Index) BuildLibIdIndex() map[int]Book {
+	out := make(map[int]Book, idx.TotalBooks())
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if _, ok := out[b.LibId]; !ok && b.LibId != 0 {
+				out[b.LibId] = b
+			}
+		}
+	}
+	return out
+}
+
+// AuthorStat is a number of books of the author.
+type AuthorStat struct {
+	Author Author
+	Count  int
+}
+
+// MostProlificAuthors returns at most n authors with the highest number of books, sorted by book count
+// in descending order. Authors with the same count are sorted by name (see Author.String).
+// Authors are matched with Author.Equals. Books with the same LibId are counted once, and
+// deleted books are skipped. If n is negative, all authors are returned.
+func (idx *Index) MostProlificAuthors(n int) []AuthorStat {
+	type authorBooks struct {
+		stat  AuthorStat
+		books map[int]struct{}
+	}
+	byKey := make(map[string]*authorBooks)
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.Deleted {
+				continue
+			}
+			for _, a := range b.Authors {
+				if a.String() == "" {
+					continue
+				}
+				k := a.key()
+				ab := byKey[k]
+				if ab == nil {
+					ab = &authorBooks{stat: AuthorStat{Author: a}, books: make(map[int]struct{})}
+					byKey[k] = ab
+				}
+				if b.LibId != 0 {
+					if _, ok := ab.books[b.LibId]; ok {
+						continue
+					}
+					ab.books[b.LibId] = struct{}{}
+				}
+				ab.stat.Count++
+			}
+		}
+	}
+	out := make([]AuthorStat, 0, len(byKey))
+	for _, ab := range byKey {
+		out = append(out, ab.stat)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Author.String() < out[j].Author.String()
+	})
+	if n >= 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Subtract returns a new index with books of idx which LibId is not present in other.
+// Books keep their archive names; archives with no books left are omitted.
+// Metadata of the result is copied from idx.
+func (idx *Index) Subtract(other *Index) *Index {
+	ids := make(map[int]struct{})
+	for _, books := range other.Archives {
+		for _, b := range books {
+			ids[b.LibId] = struct{}{}
+		}
+	}
+	out := &Index{
+		Name:        idx.Name,
+		Description: idx.Description,
+		Version:     idx.Version,
+		Structure:   idx.Structure,
+		Archives:    make(map[string][]Book),
+	}
+	for name, books := range idx.Archives {
+		var left []Book
+		for _, b := range books {
+			if _, ok := ids[b.LibId]; !ok {
+				left = append(left, b)
+			}
+		}
+		if len(left) != 0 {
+			out.Archives[name] = left
+			out.copyArchiveFile(idx, name)
+		}
+	}
+	return out
+}
+
+// RebuildFileSizes updates File.Size of all books with uncompressed sizes of files in book archives
+// from archiveDir and returns the number of updated records. Other fields are not changed.
+// Archives that do not exist are skipped, as well as books that are not found in archives.
+func (idx *Index) RebuildFileSizes(archiveDir string) (int, error) {
+	updated := 0
+	for _, name := range idx.archiveNames() {
+		zf, err := zip.OpenReader(archivePath(archiveDir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return updated, &ArchiveOpenError{Archive: name, Cause: err}
+		}
+		sizes := make(map[string]int, len(zf.File))
+		for _, f := range zf.File {
+			sizes[f.Name] = int(f.UncompressedSize64)
+		}
+		zf.Close()
+		books := idx.Archives[name]
+		for i := range books {
+			fr := &books[i].File
+			if size, ok := sizes[fr.zipName()]; ok && size != fr.Size {
+				fr.Size = size
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
+
+// copyArchiveFile copies a description of the archive zip file from another index, if it is known.
+func (idx *Index) copyArchiveFile(from *Index, name string) {
+	af, ok := from.ArchiveFiles[name]
+	if !ok {
+		return
+	}
+	if idx.ArchiveFiles == nil {
+		idx.ArchiveFiles = make(map[string]ArchiveFile)
+	}
+	idx.ArchiveFiles[name] = af
+}
+
+// ArchiveInfo describes an archive of the index with its books.
+type ArchiveInfo struct {
+	Name  string
+	Books []Book
+	// ZipSize and ModTime describe the zip file of the archive. They are zero if the zip
+	// does not exist or if its description is not known (see Index.ArchiveFiles).
+	ZipSize int64
+	ModTime time.Time
+}
+
+// ArchiveInfo returns a description of the archive with a given name. It returns false
+// if the index has no such archive. ModTime can be used to check if the archive was changed
+// since the index was opened.
+func (idx *Index) ArchiveInfo(name string) (ArchiveInfo, bool) {
+	books, ok := idx.Archives[name]
+	if !ok {
+		return ArchiveInfo{}, false
+	}
+	af := idx.ArchiveFiles[name]
+	return ArchiveInfo{Name: name, Books: books, ZipSize: af.ZipSize, ModTime: af.ModTime}, true
+}
@@ -0,0 +1,362 @@
+package inpx
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testDate = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// makeTestIndex generates an index with n books spread across 10 archives.
+func makeTestIndex(n int) *Index {
+	idx := &Index{Name: "test", Archives: make(map[string][]Book)}
+	for i := 0; i < n; i++ {
+		arch := "fb2-" + strconv.Itoa(i%10)
+		idx.Archives[arch] = append(idx.Archives[arch], Book{
+			Title:   "Book " + strconv.Itoa(i),
+			LibId:   i + 1,
+			Lang:    "en",
+			Date:    testDate.AddDate(0, 0, (i*7919)%1000),
+			Deleted: i%13 == 0,
+			File:    File{Name: strconv.Itoa(i + 1), Ext: "fb2", Archive: arch, Size: 1000 + i},
+		})
+	}
+	return idx
+}
+
+func TestFilterByDateRange(t *testing.T) {
+	idx := makeTestIndex(1000)
+	from, to := testDate.AddDate(0, 0, 100), testDate.AddDate(0, 0, 199)
+	books := idx.FilterByDateRange(from, to)
+	if len(books) != 100 {
+		t.Fatalf("unexpected books count: %d", len(books))
+	}
+	for i, b := range books {
+		if b.Date.Before(from) || b.Date.After(to) {
+			t.Fatalf("book is out of range: %v", b.Date)
+		}
+		if i > 0 && b.Date.Before(books[i-1].Date) {
+			t.Fatal("books are not sorted")
+		}
+	}
+	if n := len(idx.FilterByDateRange(time.Time{}, time.Time{})); n != 1000 {
+		t.Fatalf("unexpected books count for unbounded range: %d", n)
+	}
+	if n := len(idx.FilterByDateRange(to, from)); n != 0 {
+		t.Fatalf("unexpected books count for empty range: %d", n)
+	}
+}
+
+func BenchmarkFilterByDateRange(b *testing.B) {
+	idx := makeTestIndex(100000)
+	from, to := testDate.AddDate(0, 0, 100), testDate.AddDate(0, 0, 199)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.FilterByDateRange(from, to)
+	}
+}
+
+func TestFilterByLibRate(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {{LibId: 1, LibRate: 5}, {LibId: 2, LibRate: -1}, {LibId: 3, LibRate: 3}, {LibId: 4, LibRate: 0}},
+	}}
+	for _, c := range []struct {
+		min int
+		exp []int
+	}{
+		{4, []int{1}},
+		{3, []int{1, 3}},
+		{0, []int{1, 3, 4}},
+		{-1, []int{1, 2, 3, 4}},
+	} {
+		var ids []int
+		for _, b := range idx.FilterByLibRate(c.min) {
+			ids = append(ids, b.LibId)
+		}
+		if !reflect.DeepEqual(ids, c.exp) {
+			t.Fatalf("FilterByLibRate(%d) = %v", c.min, ids)
+		}
+	}
+}
+
+func TestCompact(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {{LibId: 1}, {LibId: 2, Deleted: true}, {LibId: 3}, {LibId: 4, Deleted: true}},
+		"b": {{LibId: 5, Deleted: true}},
+		"c": {{LibId: 6}},
+	}}
+	a := idx.Archives["a"]
+	orig := append([]Book(nil), a...)
+	if n := idx.Compact(); n != 3 {
+		t.Fatalf("unexpected number of removed books: %d", n)
+	}
+	if _, ok := idx.Archives["b"]; ok || len(idx.Archives) != 2 {
+		t.Fatalf("unexpected archives: %v", idx.Archives)
+	}
+	var ids []int
+	for _, b := range idx.AllBooks() {
+		ids = append(ids, b.LibId)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 3, 6}) {
+		t.Fatalf("unexpected books: %v", ids)
+	}
+	if !reflect.DeepEqual(a, orig) {
+		t.Fatalf("original slice was modified: %v", a)
+	}
+	if n := idx.Compact(); n != 0 {
+		t.Fatalf("unexpected number of removed books: %d", n)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	author := []Author{newAuthor([]string{"Asimov", "Isaac"})}
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Title: "Foundation", Authors: author, Lang: "en", Date: testDate.AddDate(0, 0, 2)},
+			{LibId: 2, Title: "Robots", Authors: author, Lang: "en"},
+		},
+		"b": {
+			{LibId: 3, Title: " foundation ", Authors: []Author{newAuthor([]string{"ASIMOV", "Isaac"})}, Lang: "EN", Date: testDate},
+			{LibId: 4, Title: "Foundation", Authors: author, Lang: "ru"},
+			{LibId: 5, Title: "Foundation", Authors: author, Lang: "en", Date: testDate.AddDate(0, 0, 1)},
+		},
+	}}
+	groups := idx.FindDuplicates()
+	if len(groups) != 1 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	var ids []int
+	for _, b := range groups[0] {
+		ids = append(ids, b.LibId)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 5, 1}) {
+		t.Fatalf("unexpected group: %v", ids)
+	}
+	if k1, k2 := TitleAuthorLangKey(idx.Archives["a"][0]), TitleAuthorLangKey(idx.Archives["b"][0]); k1 != k2 {
+		t.Fatalf("keys should be equal: %q vs %q", k1, k2)
+	}
+	byTitle := idx.FindDuplicatesBy(func(b Book) string { return b.Title })
+	if len(byTitle) != 1 || len(byTitle[0]) != 3 || byTitle[0][0].LibId != 4 {
+		t.Fatalf("unexpected groups: %v", byTitle)
+	}
+	if groups = idx.FindDuplicatesBy(func(b Book) string { return strconv.Itoa(b.LibId) }); groups != nil {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	b := Book{
+		LibId:   1,
+		Title:   "Foundation",
+		Authors: []Author{newAuthor([]string{"Asimov", "Isaac"})},
+		Lang:    "en",
+		File:    File{Name: "1", Ext: "fb2", Archive: "a"},
+	}
+	if fp := b.Fingerprint(); fp != "cfdcd2679052ab68" {
+		t.Fatalf("unexpected fingerprint: %q", fp)
+	}
+	o := b.Clone()
+	o.LibId, o.Title, o.Lang = 2, "  FOUNDATION ", "EN"
+	o.Authors = []Author{newAuthor([]string{"Asimov", "I."}), newAuthor([]string{"Other"})}
+	o.File = File{Name: "2", Ext: "FB2", Archive: "b", Size: 100}
+	if b.Fingerprint() != o.Fingerprint() {
+		t.Fatalf("fingerprints should be equal: %+v vs %+v", b, o)
+	}
+	for _, fn := range []func(*Book){
+		func(o *Book) { o.Title = "Foundation and Empire" },
+		func(o *Book) { o.Authors = nil },
+		func(o *Book) { o.Lang = "ru" },
+		func(o *Book) { o.File.Ext = "epub" },
+	} {
+		o := b.Clone()
+		fn(&o)
+		if b.Fingerprint() == o.Fingerprint() {
+			t.Fatalf("fingerprints should differ: %+v", o)
+		}
+	}
+	idx := &Index{Archives: map[string][]Book{"a": {b, {LibId: 3, Title: "Robots"}}, "b": {o}}}
+	if groups := idx.FindDuplicatesBy(Book.Fingerprint); len(groups) != 1 || len(groups[0]) != 2 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+}
+
+func TestRateDistribution(t *testing.T) {
+	idx := makeTestIndex(1000)
+	deleted := 0
+	for _, books := range idx.Archives {
+		for i := range books {
+			books[i].LibRate = books[i].LibId%7 - 1
+			if books[i].Deleted {
+				deleted++
+			}
+		}
+	}
+	dist := idx.RateDistribution()
+	sum := 0
+	for _, n := range dist {
+		sum += n
+	}
+	if sum != idx.TotalBooks()-deleted {
+		t.Fatalf("unexpected distribution: %v", dist)
+	}
+}
+
+func TestArchivesContaining(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"fb2-10": {{LibId: 1}, {LibId: 1}},
+		"fb2-9":  {{LibId: 2}, {LibId: 1}},
+		"fb2-11": {{LibId: 3}},
+	}}
+	if got := idx.ArchivesContaining(1); !reflect.DeepEqual(got, []string{"fb2-9", "fb2-10"}) {
+		t.Fatalf("unexpected archives: %v", got)
+	}
+	if got := idx.ArchivesContaining(4); got != nil {
+		t.Fatalf("unexpected archives: %v", got)
+	}
+}
+
+func TestBuildLibIdIndex(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"b": {{LibId: 1, Title: "B"}, {LibId: 0, Title: "none"}},
+		"a": {{LibId: 1, Title: "A"}, {LibId: 2, Title: "C"}},
+	}}
+	m := idx.BuildLibIdIndex()
+	exp := map[int]Book{1: {LibId: 1, Title: "A"}, 2: {LibId: 2, Title: "C"}}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatalf("unexpected index: %v", m)
+	}
+}
+
+func TestMostProlificAuthors(t *testing.T) {
+	doe := newAuthor([]string{"Doe", "John"})
+	doe2 := newAuthor([]string{"doe", " JOHN "})
+	roe := newAuthor([]string{"Roe", "Jane"})
+	abe := newAuthor([]string{"Abe"})
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Authors: []Author{doe}},
+			{LibId: 2, Authors: []Author{doe2, roe}},
+			{LibId: 3, Authors: []Author{abe}},
+			{LibId: 4, Authors: []Author{roe}, Deleted: true},
+		},
+		"b": {
+			{LibId: 1, Authors: []Author{doe}},
+			{LibId: 5, Authors: []Author{abe}},
+		},
+	}}
+	var got []string
+	for _, s := range idx.MostProlificAuthors(-1) {
+		got = append(got, s.Author.String()+":"+strconv.Itoa(s.Count))
+	}
+	if exp := []string{"Abe:2", "Doe John:2", "Roe Jane:1"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected authors: %q", got)
+	}
+	if top := idx.MostProlificAuthors(1); len(top) != 1 || top[0].Author.LastName != "Abe" {
+		t.Fatalf("unexpected top: %v", top)
+	}
+	if !doe.Equals(doe2) || doe.Equals(roe) {
+		t.Fatal("unexpected Equals result")
+	}
+}
+
+func TestFilterWithFiles(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"b": {{LibId: 3, File: File{Name: "3", Ext: "fb2"}}},
+		"a": {
+			{LibId: 1, File: File{Name: "1", Ext: "fb2"}},
+			{LibId: 2, File: File{Name: "2"}},
+			{LibId: 4},
+		},
+	}}
+	ids := func(books []Book) []int {
+		var out []int
+		for _, b := range books {
+			out = append(out, b.LibId)
+		}
+		return out
+	}
+	if got := ids(idx.FilterWithFiles()); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("unexpected books with files: %v", got)
+	}
+	if got := ids(idx.FilterWithoutFiles()); !reflect.DeepEqual(got, []int{2, 4}) {
+		t.Fatalf("unexpected books without files: %v", got)
+	}
+}
+
+func TestNewerThan(t *testing.T) {
+	now := time.Now()
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Date: now.AddDate(0, 0, -40)},
+			{LibId: 2, Date: now.AddDate(0, 0, -10)},
+		},
+		"b": {
+			{LibId: 3, Date: now.AddDate(0, 0, -1)},
+			{LibId: 4},
+		},
+	}}
+	var ids []int
+	for _, b := range idx.NewerThan(30 * 24 * time.Hour) {
+		ids = append(ids, b.LibId)
+	}
+	if !reflect.DeepEqual(ids, []int{3, 2}) {
+		t.Fatalf("unexpected books: %v", ids)
+	}
+}
+
+func BenchmarkNewerThan(b *testing.B) {
+	idx := makeTestIndex(100000)
+	d := time.Since(testDate.AddDate(0, 0, 900))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.NewerThan(d)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	a := &Index{Name: "a", Archives: map[string][]Book{
+		"x": {{LibId: 1}, {LibId: 2}},
+		"y": {{LibId: 3}},
+	}}
+	b := &Index{Archives: map[string][]Book{
+		"z": {{LibId: 2}, {LibId: 3}, {LibId: 4}},
+	}}
+	exp := &Index{Name: "a", Archives: map[string][]Book{
+		"x": {{LibId: 1}},
+	}}
+	if diff := a.Subtract(b); !reflect.DeepEqual(diff, exp) {
+		t.Fatalf("unexpected result: %+v", diff)
+	}
+}
+
+func TestRebuildFileSizes(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "a.zip"), map[string]string{"1.fb2": "12345", "2.fb2": "12"})
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, File: File{Name: "1", Ext: "fb2", Size: 100}},
+			{LibId: 2, File: File{Name: "2", Ext: "fb2", Size: 2}},
+			{LibId: 3, File: File{Name: "3", Ext: "fb2", Size: 3}},
+		},
+		"missing": {{LibId: 4, File: File{Name: "4", Ext: "fb2", Size: 4}}},
+	}}
+	n, err := idx.RebuildFileSizes(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("unexpected number of updated records: %d", n)
+	}
+	var sizes []int
+	for _, b := range idx.AllBooks() {
+		sizes = append(sizes, b.File.Size)
+	}
+	if !reflect.DeepEqual(sizes, []int{5, 2, 3, 4}) {
+		t.Fatalf("unexpected sizes: %v", sizes)
+	}
+}
@@ -0,0 +1,86 @@
+package inpx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// IndexInfo describes an inpx file without its books.
+type IndexInfo struct {
+	Name    string
+	Version Version
+	// Archives maps archive names to the number of records in them.
+	Archives map[string]int
+	// ZipMembers lists names of all files in the inpx.
+	ZipMembers []string
+}
+
+// countLines counts lines in the reader. The last line is counted even if it has no line terminator.
+func countLines(r io.Reader) (int, error) {
+	buf := make([]byte, 64*1024)
+	n, last := 0, byte('\n')
+	for {
+		sz, err := r.Read(buf)
+		if sz > 0 {
+			n += bytes.Count(buf[:sz], []byte{'\n'})
+			last = buf[sz-1]
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return n, err
+		}
+	}
+	if last != '\n' {
+		n++
+	}
+	return n, nil
+}
+
+// InpxInfo reads metadata of an inpx file and counts records in each archive without parsing them.
+func InpxInfo(path string) (IndexInfo, error) {
+	zf, err := zip.OpenReader(path)
+	if err == zip.ErrFormat {
+		return IndexInfo{}, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	} else if err != nil {
+		return IndexInfo{}, err
+	}
+	defer zf.Close()
+	decodeNames(&zf.Reader)
+	info := IndexInfo{Archives: make(map[string]int)}
+	for _, f := range zf.File {
+		info.ZipMembers = append(info.ZipMembers, f.Name)
+		switch f.Name {
+		case "version.info":
+			data, err := readZipFile(f)
+			if err != nil {
+				return info, fmt.Errorf("error while reading version info: %v", err)
+			}
+			info.Version = parseVersion(data)
+		case "collection.info":
+			data, err := readZipFile(f)
+			if err != nil {
+				return info, fmt.Errorf("error while reading collection info: %v", err)
+			}
+			info.Name, _ = parseCollectionInfo(string(data))
+		default:
+			if !strings.HasSuffix(f.Name, ".inp") {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return info, fmt.Errorf("error while reading inp: %v", err)
+			}
+			n, err := countLines(rc)
+			rc.Close()
+			if err != nil {
+				return info, fmt.Errorf("error while reading inp: %v", err)
+			}
+			info.Archives[strings.TrimSuffix(f.Name, ".inp")] = n
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,58 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInpxInfo(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"collection.info": "Test collection\nDescription\n",
+		"version.info":    "20200101\n",
+		"a.inp":           "1\n2\n3\n",
+		"b.inp":           "1\n2",
+		"c.inp":           "",
+	})
+	info, err := InpxInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := IndexInfo{
+		Name:       "Test collection",
+		Version:    "20200101",
+		Archives:   map[string]int{"a": 3, "b": 2, "c": 0},
+		ZipMembers: []string{"a.inp", "b.inp", "c.inp", "collection.info", "version.info"},
+	}
+	if !reflect.DeepEqual(info, exp) {
+		t.Fatalf("unexpected info: %+v", info)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"version.info": "2.0.1\r\n",
+		"a.inp":        "",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Version != "2.0.1" || index.Version.Major() != 2 {
+		t.Fatalf("unexpected version: %q", index.Version)
+	}
+	for _, c := range []struct {
+		a, b Version
+		less bool
+	}{
+		{"1.3", "1.10", true},
+		{"1.10", "1.3", false},
+		{"2.0", "2.0.1", true},
+		{"2", "2.0", false},
+		{"20200101", "20201231", true},
+		{"1.a", "1.b", true},
+	} {
+		if got := c.a.Less(c.b); got != c.less {
+			t.Errorf("%q < %q: expected %v", c.a, c.b, c.less)
+		}
+	}
+}
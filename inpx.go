@@ -5,15 +5,23 @@ import (
 	"archive/zip"
 	"bufio"
 	"bytes"
+	"compress/flate"
+	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 )
 
 // Known fields for inp files.
@@ -32,8 +40,139 @@ const (
 	FieldLang
 	FieldLibRate
 	FieldKeywords
+	// FieldFolder is a name of the archive that contains the book file. It is not a part of DefaultStructure.
+	// Books that are split across several archives list all of them, separated by commas.
+	FieldFolder
+	// FieldISBN is an ISBN of the book. It is not a part of DefaultStructure.
+	FieldISBN
 )
 
+// KnownDateFormats is a list of date formats that are accepted in inp files.
+// Formats are tried in order. Custom formats can be added before opening inpx files.
+var KnownDateFormats = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"2006/01/02",
+	"2006",
+}
+
+// parseDate parses the date in a given location using the first matching format from KnownDateFormats.
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	var first error
+	for _, layout := range KnownDateFormats {
+		t, err := time.ParseInLocation(layout, s, loc)
+		if err == nil {
+			return t, nil
+		} else if first == nil {
+			first = err
+		}
+	}
+	if first == nil {
+		first = fmt.Errorf("cannot parse date %q", s)
+	}
+	return time.Time{}, first
+}
+
+// fieldUnknown is used for fields of structure.info that are not known to the package.
+// Raw values of such fields are kept in Book.Extra.
+const fieldUnknown = -1
+
+// fieldSkipped is used for fields that should not be loaded (see WithLoadFields).
+// Values of such fields are skipped.
+const fieldSkipped = -2
+
+// FieldByName maps field names used in structure.info to field constants.
+// Custom fields can be added before opening inpx files.
+var FieldByName = map[string]int{
+	"AUTHOR":   FieldAuthor,
+	"GENRE":    FieldGenre,
+	"TITLE":    FieldTitle,
+	"SERIES":   FieldSeries,
+	"SERNO":    FieldSeriesNum,
+	"FILE":     FieldFileName,
+	"SIZE":     FieldFileSize,
+	"LIBID":    FieldLibId,
+	"DEL":      FieldDeleted,
+	"EXT":      FieldExt,
+	"DATE":     FieldDate,
+	"LANG":     FieldLang,
+	"LIBRATE":  FieldLibRate,
+	"KEYWORDS": FieldKeywords,
+	"FOLDER":   FieldFolder,
+	"ISBN":     FieldISBN,
+}
+
+// FieldName maps field constants to names used in structure.info. It is the inverse of FieldByName.
+var FieldName = map[int]string{
+	FieldAuthor:    "AUTHOR",
+	FieldGenre:     "GENRE",
+	FieldTitle:     "TITLE",
+	FieldSeries:    "SERIES",
+	FieldSeriesNum: "SERNO",
+	FieldFileName:  "FILE",
+	FieldFileSize:  "SIZE",
+	FieldLibId:     "LIBID",
+	FieldDeleted:   "DEL",
+	FieldExt:       "EXT",
+	FieldDate:      "DATE",
+	FieldLang:      "LANG",
+	FieldLibRate:   "LIBRATE",
+	FieldKeywords:  "KEYWORDS",
+	FieldFolder:    "FOLDER",
+	FieldISBN:      "ISBN",
+}
+
+// parseStructure parses field order from structure.info file (e.g. "AUTHOR;GENRE;TITLE;...").
+// It also returns names of fields that are not known to the package, in order of their appearance.
+func parseStructure(r io.Reader) ([]int, []string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		structure []int
+		extra     []string
+	)
+	for _, name := range strings.Split(string(data), ";") {
+		name = strings.ToUpper(strings.Trim(name, "\r\n\t \ufeff"))
+		if name == "" {
+			continue
+		}
+		f, ok := FieldByName[name]
+		if !ok {
+			f = fieldUnknown
+			extra = append(extra, name)
+		}
+		structure = append(structure, f)
+	}
+	if len(structure) == 0 {
+		return nil, nil, fmt.Errorf("empty structure")
+	}
+	return structure, extra, nil
+}
+
+// readZipFile reads the whole content of a zip file member.
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// parseCollectionInfo splits collection.info contents into library name (first line)
+// and description (the rest of the file).
+func parseCollectionInfo(s string) (name, desc string) {
+	name = s
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		name, desc = s[:i], s[i+1:]
+	}
+	name = strings.Trim(name, "\r\n\t \ufeff")
+	desc = strings.TrimRight(strings.TrimLeft(desc, "\ufeff"), "\r\n\t ")
+	return name, desc
+}
+
 // DefaultStructure is an inp file field order used by default.
 var DefaultStructure = []int{
 	FieldAuthor, FieldGenre, FieldTitle, FieldSeries, FieldSeriesNum,
@@ -41,12 +180,17 @@ var DefaultStructure = []int{
 	FieldLibRate, FieldKeywords,
 }
 
+// splitBy splits the string by a separator, ignoring trailing separators and empty strings.
+//
+// A separator followed by a space is not treated as a separator. Name parts in inp files
+// are separated by commas without spaces ("Толстой,Лев,Николаевич"), while commas inside
+// a single name part are followed by a space ("Jr., Smith").
 func splitBy(s string, r rune) (out []string) {
 	arr := []rune(strings.TrimRight(s, string(r)))
 	last := 0
 	for i := 0; i < len(arr); i++ {
-		if arr[i] == r {
-			if i+1 < len(arr) && arr[i+1] != ' ' {
+		if arr[i] == r && i+1 < len(arr) && arr[i+1] != ' ' {
+			if i > last {
 				out = append(out, string(arr[last:i]))
 			}
 			last = i + 1
@@ -62,17 +206,51 @@ func splitName(s string) []string {
 	return splitBy(s, ',')
 }
 
-func fieldsToBook(fields [][]byte, structure []int) (Book, error) {
+// splitAuthors splits the list of authors, ignoring trailing separators. Empty entries are preserved,
+// so positions of authors in the list do not change. Unlike name parts, authors are always
+// separated by a colon, even if it is followed by a space.
+func splitAuthors(s string) []string {
+	s = strings.TrimRight(s, ":")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ":")
+}
+
+// stripBOM removes a leading byte order mark from the string.
+func stripBOM(s string) string {
+	return strings.TrimPrefix(s, "\ufeff")
+}
+
+// sanitizeField removes null bytes and other ASCII control characters (except tab) from the field.
+// Some malformed inp files have them inside field values.
+func sanitizeField(s string) string {
+	isControl := func(r rune) bool {
+		return (r < 0x20 && r != '\t') || r == 0x7f
+	}
+	if strings.IndexFunc(s, isControl) < 0 {
+		return s
+	}
+	return strings.Map(func(r rune) rune {
+		if isControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// fieldsToBook converts inp fields to a book. Dates are parsed in a given location.
+func fieldsToBook(fields [][]byte, structure []int, loc *time.Location) (Book, error) {
 	if len(fields) < len(structure) {
-		return Book{}, fmt.Errorf("wrong fields count: %d", len(fields))
+		return Book{}, fmt.Errorf("%w: wrong fields count: %d", ErrTruncated, len(fields))
 	}
-	var errg error
+	var (
+		errg  error
+		cur   int      // field that is being parsed
+		extra []string // raw values of unknown fields
+	)
 	toStr := func() string {
-		cur := fields[0]
-		if len(cur) > 0 && cur[len(cur)-1] == ':' {
-			cur = cur[:len(cur)-1]
-		}
-		s := strings.TrimSpace(string(cur))
+		s := strings.TrimSpace(stripBOM(sanitizeField(string(fields[0]))))
 		fields = fields[1:]
 		return s
 	}
@@ -83,7 +261,7 @@ func fieldsToBook(fields [][]byte, structure []int) (Book, error) {
 		}
 		v, err := strconv.Atoi(s)
 		if err != nil && errg == nil {
-			errg = err
+			errg = fmt.Errorf("%s: %v", FieldName[cur], err)
 		}
 		return v
 	}
@@ -92,36 +270,69 @@ func fieldsToBook(fields [][]byte, structure []int) (Book, error) {
 		if s == "" {
 			return time.Time{}
 		}
-		v, err := time.Parse("2006-01-02", s)
+		v, err := parseDate(s, loc)
 		if err != nil && errg == nil {
-			errg = err
+			errg = fmt.Errorf("%s: %v", FieldName[cur], err)
 		}
 		return v
 	}
 	fieldMap := make(map[int]interface{})
 	for _, f := range structure {
 		var v interface{}
+		cur = f
 		switch f {
 		case FieldAuthor:
 			var authors []Author
-			for _, name := range strings.Split(toStr(), ":") {
-				a := Author{Name: splitName(name)}
-				for i := range a.Name {
-					a.Name[i] = strings.TrimSpace(a.Name[i])
-				}
-				authors = append(authors, a)
+			for _, name := range splitAuthors(toStr()) {
+				authors = append(authors, newAuthor(splitName(name)))
 			}
 			v = authors
 		case FieldGenre:
-			v = strings.Split(toStr(), ":")
+			var genres []string
+			// each genre is terminated with a colon
+			if s := strings.TrimSuffix(toStr(), ":"); s != "" {
+				genres = strings.Split(s, ":")
+			}
+			v = genres
+		case FieldFileName:
+			// some older generators terminate file names with a colon
+			v = strings.TrimSuffix(toStr(), ":")
 		case FieldDeleted:
 			v = toInt() != 0
 		case FieldDate:
 			v = toDate()
 		case FieldSeriesNum, FieldFileSize, FieldLibId:
 			v = toInt()
+		case FieldLibRate:
+			if fields[0] = bytes.TrimSpace(fields[0]); len(fields[0]) == 0 {
+				fields = fields[1:]
+				v = -1
+			} else {
+				v = toInt()
+			}
 		case FieldKeywords:
-			v = strings.Split(toStr(), ",")
+			var keywords []string
+			for _, k := range strings.Split(toStr(), ",") {
+				if k = strings.TrimSpace(k); k != "" {
+					keywords = append(keywords, k)
+				}
+			}
+			v = keywords
+		case FieldFolder:
+			var archives []string
+			for _, name := range strings.Split(toStr(), ",") {
+				if name = strings.TrimSpace(name); name != "" {
+					archives = append(archives, name)
+				}
+			}
+			v = archives
+		case fieldUnknown:
+			extra = append(extra, string(fields[0]))
+			fields = fields[1:]
+			continue
+		case fieldSkipped:
+			fields = fields[1:]
+			continue
 		default:
 			v = toStr()
 		}
@@ -132,7 +343,7 @@ func fieldsToBook(fields [][]byte, structure []int) (Book, error) {
 			reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(v))
 		}
 	}
-	var record Book
+	record := Book{LibRate: -1}
 	setField(FieldAuthor, &record.Authors)
 	setField(FieldGenre, &record.Genres)
 	setField(FieldTitle, &record.Title)
@@ -145,103 +356,500 @@ func fieldsToBook(fields [][]byte, structure []int) (Book, error) {
 	setField(FieldDeleted, &record.Deleted)
 	setField(FieldDate, &record.Date)
 	setField(FieldLang, &record.Lang)
+	setField(FieldLibRate, &record.LibRate)
+	setField(FieldISBN, &record.ISBN)
+	setField(FieldKeywords, &record.Keywords)
+	record.Extra = extra
+	if archives, _ := fieldMap[FieldFolder].([]string); len(archives) != 0 {
+		record.File.Archive = archives[0]
+		if len(archives) > 1 {
+			record.File.AdditionalArchives = archives[1:]
+		}
+	}
 	return record, errg
 }
 
 // OpenWithStructure reads whole library index from an inpx file
 // using a provided field structure for individual inp files.
+// If inpx contains a structure.info file, the structure from it is used instead.
 func OpenWithStructure(path string, structure []int) (*Index, error) {
+	return Open(path, WithStructure(structure))
+}
+
+// Open reads whole library index from an inpx file using provided options. The field structure
+// is read from structure.info if the inpx has it; otherwise the structure set by WithStructure
+// (DefaultStructure by default) is used.
+func Open(path string, opts ...Option) (*Index, error) {
+	return OpenContext(context.Background(), path, opts...)
+}
+
+// OpenWithOptions reads whole library index from an inpx file using provided options.
+//
+// Deprecated: use Open, which accepts the same options.
+func OpenWithOptions(path string, opts ...Option) (*Index, error) {
+	return Open(path, opts...)
+}
+
+// OpenContext is like Open, but stops reading the index when the context is cancelled.
+// The context is checked between inp files and periodically while reading each of them.
+// With WithLazyLoad, only opening the index is bound by the context, not Index.Archive.
+func OpenContext(ctx context.Context, path string, opts ...Option) (*Index, error) {
+	c := newConfig(opts)
 	zf, err := zip.OpenReader(path)
-	if err != nil {
+	if err == zip.ErrFormat {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	} else if err != nil {
 		return nil, err
 	}
-	defer zf.Close()
+	index, err := readIndex(ctx, &zf.Reader, filepath.Dir(path), c)
+	if err != nil || index.lazy == nil {
+		zf.Close()
+		return index, err
+	}
+	index.lazy.closer = zf
+	return index, nil
+}
+
+// OpenReaderAt reads whole library index from an inpx file provided as io.ReaderAt.
+// Since there is no directory for book archives, File.Dir of all books is empty.
+func OpenReaderAt(r io.ReaderAt, size int64, opts ...Option) (*Index, error) {
+	c := newConfig(opts)
+	zr, err := zip.NewReader(r, size)
+	if err == zip.ErrFormat {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	} else if err != nil {
+		return nil, err
+	}
+	return readIndex(context.Background(), zr, "", c)
+}
+
+// OpenBytes reads whole library index from an inpx file contents
+// using a provided field structure for individual inp files.
+func OpenBytes(data []byte, structure []int) (*Index, error) {
+	return OpenReaderAt(bytes.NewReader(data), int64(len(data)), WithStructure(structure))
+}
+
+// decodeNames converts names of zip members that are not valid UTF-8 from Windows-1252.
+// Such names are written by some older inpx generators.
+func decodeNames(zr *zip.Reader) {
+	dec := charmap.Windows1252.NewDecoder()
+	for _, f := range zr.File {
+		if utf8.ValidString(f.Name) {
+			continue
+		}
+		if name, err := dec.String(f.Name); err == nil {
+			f.Name = name
+		}
+	}
+}
 
-	dir := filepath.Dir(path)
+// readHeader reads library metadata (name, version and field structure) from an inpx zip.
+// Archives of the returned index are empty. Names of zip members are decoded with decodeNames.
+func readHeader(zr *zip.Reader, c *config) (*Index, error) {
+	decodeNames(zr)
 	index := &Index{
-		Archives: make(map[string][]Book),
+		Structure: c.structure,
+		Archives:  make(map[string][]Book),
 	}
-	total := 0
-	for _, f := range zf.File {
+	for _, f := range zr.File {
 		switch f.Name {
-		case "version.info":
-			rc, err := f.Open()
+		case "structure.info":
+			data, err := readZipFile(f)
+			if err == nil {
+				index.Structure, index.ExtraFields, err = parseStructure(bytes.NewReader(data))
+			}
 			if err != nil {
-				return nil, fmt.Errorf("error while reading version info: %v", err)
+				return nil, fmt.Errorf("error while reading structure info: %v", err)
 			}
-			_, err = fmt.Fscan(rc, &index.Version)
-			rc.Close()
+		case "version.info":
+			data, err := readZipFile(f)
 			if err != nil {
 				return nil, fmt.Errorf("error while reading version info: %v", err)
 			}
+			index.Version = parseVersion(data)
 		case "collection.info":
-			rc, err := f.Open()
-			if err != nil {
-				return nil, fmt.Errorf("error while reading collection info: %v", err)
-			}
-			br := bufio.NewReader(rc)
-			index.Name, err = br.ReadString('\n')
-			index.Name = strings.Trim(index.Name, "\n\t \ufeff")
-			rc.Close()
+			data, err := readZipFile(f)
 			if err != nil {
 				return nil, fmt.Errorf("error while reading collection info: %v", err)
 			}
+			index.Name, index.Description = parseCollectionInfo(string(data))
 		default:
 			if !strings.HasSuffix(f.Name, ".inp") {
-				log.Println("unknown file:", f.Name)
-				continue
-			}
-			pack := strings.TrimSuffix(f.Name, ".inp")
-			rc, err := f.Open()
-			if err != nil {
-				return nil, fmt.Errorf("error while reading inp: %v", err)
-			}
-			br := bufio.NewReader(rc)
-			var recs []Book
-			for {
-				line, err := br.ReadBytes('\n')
-				if err == io.EOF {
-					break
-				}
-				if err != nil {
-					rc.Close()
-					return nil, fmt.Errorf("error while reading inp: %v", err)
-				}
-				if len(line) > 0 {
-					line = line[:len(line)-1]
-				}
-				rec, err := fieldsToBook(bytes.Split(line, []byte{0x04}), structure)
-				if err != nil {
-					log.Println("err:", err)
+				if c.logger != nil {
+					c.logger("unknown file in inpx", "name", f.Name)
 				} else {
-					rec.File.Dir = dir
-					rec.File.Archive = pack
-					recs = append(recs, rec)
+					log.Println("unknown file:", f.Name)
 				}
 			}
-			rc.Close()
-			{
-				nrec := make([]Book, len(recs))
-				copy(nrec, recs)
-				recs = nrec
+		}
+	}
+	return index, nil
+}
+
+// inpArchive returns an archive name for the inp file. It returns false if the file is not
+// an inp file, or if the archive should be skipped.
+func inpArchive(f *zip.File, c *config) (string, bool) {
+	if !strings.HasSuffix(f.Name, ".inp") {
+		return "", false
+	}
+	pack := strings.TrimSuffix(f.Name, ".inp")
+	return pack, c.includeArchive(pack)
+}
+
+// readIndex reads library index from an inpx zip. Dir is a directory of book archives.
+func readIndex(ctx context.Context, zr *zip.Reader, dir string, c *config) (*Index, error) {
+	index, err := readHeader(zr, c)
+	if err != nil {
+		return nil, err
+	}
+	structure := c.loadStructure(index.Structure)
+	c.collectErrs = true
+	var inps []*zip.File
+	for _, f := range zr.File {
+		if _, ok := inpArchive(f, c); ok {
+			inps = append(inps, f)
+		}
+	}
+	if c.lazy {
+		index.lazy = &lazyArchives{c: c, dir: dir, structure: structure, files: make(map[string]*zip.File)}
+		for _, f := range inps {
+			pack, _ := inpArchive(f, c)
+			index.lazy.files[pack] = f
+		}
+		if dir != "" {
+			index.statArchives(dir)
+		}
+		return index, nil
+	}
+
+	type result struct {
+		pack string
+		recs []Book
+		err  error
+	}
+	jobs := make(chan *zip.File)
+	results := make(chan result)
+	for i := 0; i < c.concurrency; i++ {
+		go func() {
+			for f := range jobs {
+				pack, _ := inpArchive(f, c)
+				recs, err := readInp(ctx, f, pack, dir, structure, c)
+				results <- result{pack: pack, recs: recs, err: err}
 			}
-			index.Archives[pack] = recs
-			total += len(recs)
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for _, f := range inps {
+			jobs <- f
+		}
+	}()
+	var errg error
+	for i := range inps {
+		r := <-results
+		if r.err != nil && errg == nil {
+			errg = r.err
+		}
+		index.Archives[r.pack] = r.recs
+		if c.progress != nil {
+			c.progress(i+1, len(inps))
+		}
+	}
+	if errg == nil {
+		errg = ctx.Err()
+	}
+	if errg != nil {
+		return nil, errg
+	}
+	index.ParseErrors = c.takeParseErrors()
+	if dir != "" {
+		index.statArchives(dir)
+	}
+	if c.offsets && dir != "" {
+		for pack, books := range index.Archives {
+			resolveOffsets(archivePath(dir, pack), books)
 		}
 	}
 	return index, nil
 }
 
-// Open reads whole library index from an inpx file.
-func Open(path string) (*Index, error) {
-	return OpenWithStructure(path, DefaultStructure)
+// resolveOffsets populates data offsets of book files from a given archive.
+// Books that are not found in the archive, or that are stored in other archives
+// (see FieldFolder), are left unchanged.
+func resolveOffsets(path string, books []Book) {
+	zf, err := zip.OpenReader(path)
+	if err != nil {
+		return
+	}
+	defer zf.Close()
+	files := make(map[string]*zip.File, len(zf.File))
+	for _, f := range zf.File {
+		files[f.Name] = f
+	}
+	for i := range books {
+		fr := &books[i].File
+		f := files[fr.zipName()]
+		if f == nil || fr.AbsolutePath() != path {
+			continue
+		}
+		off, err := f.DataOffset()
+		if err != nil {
+			continue
+		}
+		fr.Offset = off
+		fr.CompressedSize = int64(f.CompressedSize64)
+		fr.Method = f.Method
+	}
+}
+
+// ctxCheckBooks is a number of books after which readInp checks if the context is cancelled.
+const ctxCheckBooks = 1000
+
+// readInp reads all books from a single inp file.
+func readInp(ctx context.Context, f *zip.File, pack, dir string, structure []int, c *config) ([]Book, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("error while reading inp: %v", err)
+	}
+	defer rc.Close()
+	r := newInpReader(rc, pack, dir, structure, c)
+	var recs []Book
+	for {
+		rec, err := r.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+		if len(recs)%ctxCheckBooks == 0 {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	nrec := make([]Book, len(recs))
+	copy(nrec, recs)
+	return nrec, nil
+}
+
+// inpReader reads books from a single inp file.
+type inpReader struct {
+	c         *config
+	structure []int
+	pack, dir string
+	br        *bufio.Reader
+	dec       *encoding.Decoder
+	fallback  *encoding.Decoder // decodes lines that are not valid UTF-8 if dec is not set
+	line      int               // number of lines read
+	off       int64             // number of bytes read
+}
+
+func newInpReader(r io.Reader, pack, dir string, structure []int, c *config) *inpReader {
+	ir := &inpReader{
+		c: c, structure: structure,
+		pack: pack, dir: dir,
+		br: bufio.NewReader(r),
+	}
+	if c.encoding != nil {
+		ir.dec = c.encoding.NewDecoder()
+	}
+	return ir
+}
+
+// next returns the next book from the inp file, or io.EOF if there are no more books.
+// Malformed records are reported to the error handler and skipped.
+func (r *inpReader) next() (Book, error) {
+	c := r.c
+	for {
+		line, err := r.br.ReadBytes(c.lineSep)
+		if err == io.EOF && len(line) == 0 {
+			return Book{}, io.EOF
+		} else if err != nil && err != io.EOF {
+			return Book{}, fmt.Errorf("error while reading inp: %v", err)
+		}
+		r.line++
+		r.off += int64(len(line))
+		raw := c.trimLine(line)
+		line = raw
+		if r.dec != nil {
+			line, err = r.dec.Bytes(line)
+			if err != nil {
+				return Book{}, fmt.Errorf("error while decoding inp: %v", err)
+			}
+		} else if !utf8.Valid(line) {
+			// older MyHomeLib versions write inp files in Windows-1251
+			if r.fallback == nil {
+				r.fallback = charmap.Windows1251.NewDecoder()
+			}
+			line, err = r.fallback.Bytes(line)
+			if err != nil {
+				return Book{}, fmt.Errorf("error while decoding inp: %v", err)
+			}
+		}
+		rec, err := fieldsToBook(bytes.Split(line, []byte{c.fieldSep}), r.structure, c.dateLoc)
+		if err != nil {
+			perr := &ParseError{Archive: r.pack, Line: r.line, Raw: raw, Cause: err}
+			if c.strict {
+				return Book{}, perr
+			}
+			c.handleError(perr)
+			continue
+		}
+		rec.File.Dir = r.dir
+		if rec.File.Archive == "" {
+			rec.File.Archive = r.pack
+		}
+		if c.preserveRaw {
+			rec.Raw = raw
+		}
+		return rec, nil
+	}
 }
 
 // Index describes an inpx file information.
 type Index struct {
-	Name     string
-	Version  int
+	Name string
+	// Description is the text that follows the name in collection.info.
+	Description string
+	Version     Version
+	// Structure is a field order that was used to read inp files.
+	Structure []int
+	// ExtraFields lists names of structure.info fields that are not known to the package,
+	// in the order they appear in Structure. Their values are kept in Book.Extra.
+	ExtraFields []string
+	// Archives holds books of each archive. If the index was opened with WithLazyLoad,
+	// it only contains archives that were loaded (see Archive and LoadAll).
 	Archives map[string][]Book
+	// ArchiveFiles describes zip files of book archives, keyed by archive name.
+	// It is populated by Open on a best-effort basis: if the archive does not exist,
+	// the description is empty. See ArchiveInfo.
+	ArchiveFiles map[string]ArchiveFile
+	// ParseErrors lists malformed records that were skipped while reading the index,
+	// sorted by archive and line number. Errors are also reported as usual (see WithErrorHandler).
+	ParseErrors []*ParseError
+
+	lazy *lazyArchives // inp files that are not parsed yet, see WithLazyLoad
+}
+
+// lazyArchives holds inp files of the index opened with WithLazyLoad. See Index.Archive.
+type lazyArchives struct {
+	c         *config
+	dir       string
+	structure []int
+	closer    io.Closer
+
+	mu     sync.Mutex
+	files  map[string]*zip.File // inp files of archives that are not loaded yet
+	closed bool
+}
+
+// Archive returns books of the archive. If the index was opened with WithLazyLoad,
+// the inp file of the archive is parsed on the first access. Archive can be called
+// concurrently, but not concurrently with direct access to Archives.
+func (idx *Index) Archive(name string) ([]Book, error) {
+	if idx.lazy != nil {
+		l := idx.lazy
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if f, ok := l.files[name]; ok {
+			if l.closed {
+				return nil, fmt.Errorf("archive %s is not loaded: index is closed", name)
+			}
+			books, err := readInp(context.Background(), f, name, l.dir, l.structure, l.c)
+			if err != nil {
+				return nil, err
+			}
+			if l.c.offsets && l.dir != "" {
+				resolveOffsets(archivePath(l.dir, name), books)
+			}
+			idx.Archives[name] = books
+			idx.ParseErrors = append(idx.ParseErrors, l.c.takeParseErrors()...)
+			delete(l.files, name)
+			return books, nil
+		}
+	}
+	books, ok := idx.Archives[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: archive %s", os.ErrNotExist, name)
+	}
+	return books, nil
+}
+
+// LoadAll loads all archives that were not loaded yet (see WithLazyLoad).
+// It is a no-op for indexes loaded eagerly.
+func (idx *Index) LoadAll() error {
+	for _, name := range idx.UnloadedArchives() {
+		if _, err := idx.Archive(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnloadedArchives returns names of archives that are not loaded yet, in natural order (see NaturalLess).
+// Only indexes opened with WithLazyLoad have such archives.
+func (idx *Index) UnloadedArchives() []string {
+	if idx.lazy == nil {
+		return nil
+	}
+	l := idx.lazy
+	l.mu.Lock()
+	names := make([]string, 0, len(l.files))
+	for name := range l.files {
+		names = append(names, name)
+	}
+	l.mu.Unlock()
+	sort.Slice(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+	return names
+}
+
+// Close releases the inpx file that is kept open by WithLazyLoad. Archives that were not
+// loaded yet cannot be accessed after Close. It is a no-op for indexes loaded eagerly.
+func (idx *Index) Close() error {
+	if idx.lazy == nil {
+		return nil
+	}
+	l := idx.lazy
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// ArchiveFile describes a zip file of the book archive.
+type ArchiveFile struct {
+	ZipSize int64     // size of the zip file
+	ModTime time.Time // modification time of the zip file
+}
+
+// statArchives populates ArchiveFiles for all archives of the index from a given directory.
+func (idx *Index) statArchives(dir string) {
+	names := idx.archiveNames()
+	if idx.lazy != nil {
+		for name := range idx.lazy.files {
+			names = append(names, name)
+		}
+	}
+	idx.ArchiveFiles = make(map[string]ArchiveFile, len(names))
+	for _, pack := range names {
+		var af ArchiveFile
+		if fi, err := os.Stat(archivePath(dir, pack)); err == nil {
+			af = ArchiveFile{ZipSize: fi.Size(), ModTime: fi.ModTime()}
+		}
+		idx.ArchiveFiles[pack] = af
+	}
 }
 
 type multiReadCloser struct {
@@ -249,61 +857,479 @@ type multiReadCloser struct {
 	closers []io.Closer
 }
 
+// closeErrors combines errors returned by multiple Close calls.
+type closeErrors []error
+
+func (e closeErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return "close errors: " + strings.Join(msgs, "; ")
+}
+
+func (e closeErrors) Unwrap() []error {
+	return e
+}
+
+// Close closes all underlying closers and returns a combined error, if any of them fail.
 func (mc multiReadCloser) Close() error {
+	var errs closeErrors
 	for _, c := range mc.closers {
-		c.Close()
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) != 0 {
+		return errs
 	}
 	return nil
 }
 
 // Author describes an author.
 type Author struct {
-	Name []string
+	// Name contains all parts of author's name in the order from inp file.
+	//
+	// Deprecated: use LastName, FirstName and MiddleName.
+	Name []string `db:"-"`
+
+	LastName   string `db:"last_name"`
+	FirstName  string `db:"first_name"`
+	MiddleName string `db:"middle_name"`
+}
+
+// newAuthor creates an author from name parts in inp order: last name, first name, middle name.
+func newAuthor(parts []string) Author {
+	a := Author{Name: parts}
+	for i := range a.Name {
+		a.Name[i] = strings.TrimSpace(a.Name[i])
+	}
+	if len(a.Name) > 0 {
+		a.LastName = a.Name[0]
+	}
+	if len(a.Name) > 1 {
+		a.FirstName = a.Name[1]
+	}
+	if len(a.Name) > 2 {
+		a.MiddleName = strings.Join(a.Name[2:], " ")
+	}
+	return a
+}
+
+// FullName returns author's name in "FirstName MiddleName LastName" form. Empty parts are omitted.
+func (a Author) FullName() string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{a.FirstName, a.MiddleName, a.LastName} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// String returns author's name in "LastName FirstName MiddleName" form, as used in library catalogs.
+// Empty parts are omitted.
+func (a Author) String() string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{a.LastName, a.FirstName, a.MiddleName} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// key returns a normalized name of the author that is used to compare authors.
+func (a Author) key() string {
+	return normalizeText(a.LastName) + "\x00" + normalizeText(a.FirstName) + "\x00" + normalizeText(a.MiddleName)
+}
+
+// Equals checks if two authors have the same name. Names are compared case-insensitively,
+// ignoring extra spaces. Deprecated Name field is not compared.
+func (a Author) Equals(other Author) bool {
+	return a.key() == other.key()
+}
+
+// MarshalText implements encoding.TextMarshaler. Author is encoded as a full name.
+func (a Author) MarshalText() ([]byte, error) {
+	return []byte(a.FullName()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. It parses a full name in the
+// "FirstName MiddleName LastName" form. A single word is treated as a last name.
+func (a *Author) UnmarshalText(text []byte) error {
+	words := strings.Fields(string(text))
+	var parts []string
+	switch len(words) {
+	case 0:
+	case 1:
+		parts = words
+	case 2:
+		parts = []string{words[1], words[0]}
+	default:
+		n := len(words)
+		parts = []string{words[n-1], words[0], strings.Join(words[1:n-1], " ")}
+	}
+	*a = newAuthor(parts)
+	return nil
 }
 
 // File describes a book file in archive.
 type File struct {
-	Name    string
-	Ext     string
-	Dir     string
-	Archive string
-	Size    int
+	Name    string `db:"name"`
+	Ext     string `db:"ext"`
+	Dir     string `db:"dir"`
+	Archive string `db:"archive"`
+	Size    int    `db:"size"`
+
+	// Offset, CompressedSize and Method describe the location of compressed file data in the archive.
+	// They are only populated if the index was opened with WithFileOffsets option.
+	Offset         int64  `db:"offset"`
+	CompressedSize int64  `db:"compressed_size"`
+	Method         uint16 `db:"method"`
+
+	// AdditionalArchives lists archives with the remaining parts of the file, if the file
+	// is split across several archives. Parts are concatenated by Open. Parts are only set
+	// if the inp file has FieldFolder.
+	AdditionalArchives []string `db:"-"`
+}
+
+// archivePath returns a path of the book archive with a given name.
+// The ".zip" extension is added, unless the name already has it.
+func archivePath(dir, archive string) string {
+	if !strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		archive += ".zip"
+	}
+	return filepath.Join(dir, archive)
+}
+
+// AbsolutePath returns a path of the archive that contains the file.
+// The path is relative to the working directory if the index was opened with a relative path.
+func (fr File) AbsolutePath() string {
+	return archivePath(fr.Dir, fr.Archive)
+}
+
+// RelativePath returns a path of the file relative to the directory of book archives,
+// in the "archive.zip/name.ext" form.
+func (fr File) RelativePath() string {
+	return filepath.Join(archivePath("", fr.Archive), fr.zipName())
+}
+
+// zipName returns the name of the file inside the archive.
+func (fr File) zipName() string {
+	return fr.Name + "." + fr.Ext
+}
+
+// openArchive opens the archive that contains the file. Errors are returned as ArchiveOpenError.
+func (fr File) openArchive() (*zip.ReadCloser, error) {
+	zf, err := zip.OpenReader(fr.AbsolutePath())
+	if err != nil {
+		return nil, &ArchiveOpenError{Archive: fr.Archive, Cause: err}
+	}
+	return zf, nil
+}
+
+// find looks up the book file in the archive.
+func (fr File) find(zr *zip.Reader) *zip.File {
+	name := fr.zipName()
+	for _, f := range zr.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
 }
 
-// Open opens a book file from archive.
+// splitParts returns parts of the file, one for each archive (see AdditionalArchives).
+func (fr File) splitParts() []File {
+	if len(fr.AdditionalArchives) == 0 {
+		return []File{fr}
+	}
+	out := make([]File, 0, 1+len(fr.AdditionalArchives))
+	for _, name := range append([]string{fr.Archive}, fr.AdditionalArchives...) {
+		part := fr
+		part.Archive, part.AdditionalArchives = name, nil
+		out = append(out, part)
+	}
+	return out
+}
+
+// openParts opens all parts of the file with a given function and reads them one after another.
+func (fr File) openParts(open func(part File) (io.ReadCloser, error)) (io.ReadCloser, error) {
+	if len(fr.AdditionalArchives) == 0 {
+		return open(fr)
+	}
+	var (
+		readers []io.Reader
+		closers []io.Closer
+	)
+	for _, part := range fr.splitParts() {
+		rc, err := open(part)
+		if err != nil {
+			multiReadCloser{closers: closers}.Close()
+			return nil, err
+		}
+		readers = append(readers, rc)
+		closers = append(closers, rc)
+	}
+	return multiReadCloser{Reader: io.MultiReader(readers...), closers: closers}, nil
+}
+
+// Open opens a book file from archive. If the file is split across several archives
+// (see AdditionalArchives), all parts are read one after another.
 func (fr File) Open() (io.ReadCloser, error) {
-	zfile, err := zip.OpenReader(filepath.Join(fr.Dir, fr.Archive+".zip"))
+	return fr.openParts(File.openPart)
+}
+
+// openPart opens a book file from a single archive, ignoring AdditionalArchives.
+func (fr File) openPart() (io.ReadCloser, error) {
+	zfile, err := fr.openArchive()
 	if err != nil {
 		return nil, err
 	}
-	for _, f := range zfile.File {
-		if f.Name == fr.Name+"."+fr.Ext {
-			file, err := f.Open()
-			if err != nil {
-				zfile.Close()
-				return nil, err
-			}
-			return multiReadCloser{
-				Reader:  file,
-				closers: []io.Closer{file, zfile},
-			}, nil
+	f := fr.find(&zfile.Reader)
+	if f == nil {
+		zfile.Close()
+		return nil, &MissingFileError{File: fr}
+	}
+	file, err := f.Open()
+	if err != nil {
+		zfile.Close()
+		return nil, err
+	}
+	return multiReadCloser{
+		Reader:  file,
+		closers: []io.Closer{file, zfile},
+	}, nil
+}
+
+// OpenFast opens a book file from archive using a known data offset, without reading
+// the zip directory. It falls back to Open if the offset is not known, or if the file
+// is split across several archives.
+// Unlike Open, it does not verify the checksum of the file.
+func (fr File) OpenFast() (io.ReadCloser, error) {
+	if fr.Offset == 0 || len(fr.AdditionalArchives) != 0 {
+		return fr.Open()
+	}
+	f, err := os.Open(fr.AbsolutePath())
+	if err != nil {
+		return nil, &ArchiveOpenError{Archive: fr.Archive, Cause: err}
+	}
+	data := io.NewSectionReader(f, fr.Offset, fr.CompressedSize)
+	switch fr.Method {
+	case zip.Store:
+		return multiReadCloser{Reader: data, closers: []io.Closer{f}}, nil
+	case zip.Deflate:
+		fl := flate.NewReader(data)
+		return multiReadCloser{Reader: fl, closers: []io.Closer{fl, f}}, nil
+	}
+	f.Close()
+	return nil, fmt.Errorf("%w: compression method %d", ErrUnsupportedFormat, fr.Method)
+}
+
+// openRaw opens the book file from archive without decompressing it.
+// Files split across several archives cannot be opened this way, ErrSplitFile is returned for them.
+func (fr File) openRaw() (*zip.FileHeader, io.ReadCloser, error) {
+	if len(fr.AdditionalArchives) != 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrSplitFile, fr.RelativePath())
+	}
+	zfile, err := fr.openArchive()
+	if err != nil {
+		return nil, nil, err
+	}
+	f := fr.find(&zfile.Reader)
+	if f == nil {
+		zfile.Close()
+		return nil, nil, &MissingFileError{File: fr}
+	}
+	data, err := f.OpenRaw()
+	if err != nil {
+		zfile.Close()
+		return nil, nil, err
+	}
+	hdr := f.FileHeader
+	return &hdr, multiReadCloser{Reader: data, closers: []io.Closer{zfile}}, nil
+}
+
+// OpenCompressed opens a book file from archive without decompressing it. It returns
+// the compression method of the file (zip.Store or zip.Deflate) and a reader of raw compressed data.
+// It returns ErrSplitFile if the file is split across several archives.
+func (fr File) OpenCompressed() (uint16, io.ReadCloser, error) {
+	hdr, rc, err := fr.openRaw()
+	if err != nil {
+		return 0, nil, err
+	}
+	return hdr.Method, rc, nil
+}
+
+// Exists checks if the book file exists in the archive without reading its content.
+// If the file is split across several archives, all parts must exist.
+func (fr File) Exists() (bool, error) {
+	for _, part := range fr.splitParts() {
+		zfile, err := part.openArchive()
+		if err != nil {
+			return false, err
+		}
+		ok := part.find(&zfile.Reader) != nil
+		zfile.Close()
+		if !ok {
+			return false, nil
 		}
 	}
-	zfile.Close()
-	return nil, os.ErrNotExist
+	return true, nil
 }
 
 // Book describes a book in archive.
+//
+// Struct tags allow scanning books from SQL tables with sqlx (see BooksTableSQL).
 type Book struct {
-	Authors   []Author
-	Genres    []string
-	Title     string
-	Series    string
-	SeriesNum int
-	File      File
-	LibId     int
-	Deleted   bool
-	Date      time.Time
-	Lang      string
-	//Keywords  []string
+	Authors   []Author  `db:"-"`
+	Genres    []string  `db:"-"`
+	Title     string    `db:"title"`
+	Series    string    `db:"series"`
+	SeriesNum int       `db:"series_num"`
+	File      File      `db:"file"`
+	LibId     int       `db:"lib_id"`
+	Deleted   bool      `db:"deleted"`
+	Date      time.Time `db:"date"`
+	Lang      string    `db:"lang"`
+	// LibRate is a library rating of the book (usually 0-5), or -1 if the book is not rated.
+	LibRate int `db:"lib_rate"`
+	// ISBN of the book, as written in the inp file. It is only set if the structure has FieldISBN.
+	ISBN string `db:"isbn"`
+	// Keywords of the book. Empty keywords are skipped.
+	Keywords []string `db:"-"`
+	// Extra holds raw values of fields that are not known to the package (see Index.ExtraFields).
+	// They are written back unchanged when the index is saved.
+	Extra []string `db:"-"`
+
+	// Raw is an original inp line of the record. It is only set if WithPreserveRaw is used.
+	Raw []byte `db:"-"`
+}
+
+// HasFile checks if the book record has an associated file. Some records only describe metadata.
+func (b Book) HasFile() bool {
+	return b.File.Name != "" && b.File.Ext != ""
+}
+
+// String returns a one line summary of the book in the following form:
+//
+//	[LibId] Title / Author1, Author2 (Series #N) [Lang] ext
+func (b Book) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%d] %s", b.LibId, b.Title)
+	if len(b.Authors) != 0 {
+		names := make([]string, 0, len(b.Authors))
+		for _, a := range b.Authors {
+			names = append(names, a.FullName())
+		}
+		sb.WriteString(" / ")
+		sb.WriteString(strings.Join(names, ", "))
+	}
+	if b.Series != "" {
+		sb.WriteString(" (" + b.Series)
+		if b.SeriesNum != 0 {
+			fmt.Fprintf(&sb, " #%d", b.SeriesNum)
+		}
+		sb.WriteString(")")
+	}
+	if b.Lang != "" {
+		sb.WriteString(" [" + b.Lang + "]")
+	}
+	if b.File.Name != "" {
+		sb.WriteString(" " + b.File.Ext)
+	}
+	return sb.String()
+}
+
+// Clone returns a deep copy of the book.
+func (b Book) Clone() Book {
+	if b.Authors != nil {
+		authors := make([]Author, len(b.Authors))
+		for i, a := range b.Authors {
+			if a.Name != nil {
+				a.Name = append([]string(nil), a.Name...)
+			}
+			authors[i] = a
+		}
+		b.Authors = authors
+	}
+	if b.Genres != nil {
+		b.Genres = append([]string(nil), b.Genres...)
+	}
+	if b.Keywords != nil {
+		b.Keywords = append([]string(nil), b.Keywords...)
+	}
+	if b.Extra != nil {
+		b.Extra = append([]string(nil), b.Extra...)
+	}
+	if b.Raw != nil {
+		b.Raw = append([]byte(nil), b.Raw...)
+	}
+	if b.File.AdditionalArchives != nil {
+		b.File.AdditionalArchives = append([]string(nil), b.File.AdditionalArchives...)
+	}
+	return b
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func equalAuthors(a, b []Author) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalStrings(a[i].parts(), b[i].parts()) {
+			return false
+		}
+	}
+	return true
+}
+
+// ContentEqual checks if books have the same title, authors, genres, series, language and LibId.
+// File information and deleted flag are ignored.
+func (b Book) ContentEqual(other Book) bool {
+	return b.Title == other.Title &&
+		b.Series == other.Series && b.SeriesNum == other.SeriesNum &&
+		b.Lang == other.Lang && b.LibId == other.LibId &&
+		equalAuthors(b.Authors, other.Authors) &&
+		equalStrings(b.Genres, other.Genres)
+}
+
+// MetaEqual checks if all fields of the books are equal, except the location of the archive
+// (File.Dir and File.Archive) and Raw.
+func (b Book) MetaEqual(other Book) bool {
+	if !b.ContentEqual(other) || b.Deleted != other.Deleted || !b.Date.Equal(other.Date) {
+		return false
+	}
+	if b.LibRate != other.LibRate || b.ISBN != other.ISBN || !equalStrings(b.Keywords, other.Keywords) ||
+		!equalStrings(b.Extra, other.Extra) {
+		return false
+	}
+	f1, f2 := b.File, other.File
+	f1.Dir, f1.Archive, f1.AdditionalArchives = "", "", nil
+	f2.Dir, f2.Archive, f2.AdditionalArchives = "", "", nil
+	return reflect.DeepEqual(f1, f2)
 }
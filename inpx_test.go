@@ -1,10 +1,28 @@
 package inpx
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
 )
 
 var testInpxPath = os.Getenv("INPX_PATH")
@@ -42,3 +60,1134 @@ func TestInpx(t *testing.T) {
 		break
 	}
 }
+
+// writeTestZip writes a zip file with given members.
+func writeTestZip(t testing.TB, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeTestInpx writes an inpx file with given members into a temporary directory.
+func writeTestInpx(t testing.TB, files map[string]string) string {
+	path := filepath.Join(t.TempDir(), "test.inpx")
+	writeTestZip(t, path, files)
+	return path
+}
+
+func TestStructureInfo(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"collection.info": "Test collection\n",
+		"version.info":    "20200101\n",
+		"structure.info":  "TITLE;AUTHOR;LIBID;UNKNOWN;LANG;\r\n",
+		"fb2-001.inp":     "Title\x04Last,First,Middle:\x0442\x04x\x04en\x04\n",
+	})
+	index, err := OpenWithStructure(path, DefaultStructure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []int{FieldTitle, FieldAuthor, FieldLibId, fieldUnknown, FieldLang}
+	if !reflect.DeepEqual(index.Structure, exp) {
+		t.Fatalf("unexpected structure: %v", index.Structure)
+	}
+	books := index.Archives["fb2-001"]
+	if len(books) != 1 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	b := books[0]
+	if b.Title != "Title" || b.LibId != 42 || b.Lang != "en" ||
+		!reflect.DeepEqual(b.Authors, []Author{{
+			Name:     []string{"Last", "First", "Middle"},
+			LastName: "Last", FirstName: "First", MiddleName: "Middle",
+		}}) {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+	if name := b.Authors[0].FullName(); name != "First Middle Last" {
+		t.Fatalf("unexpected full name: %q", name)
+	}
+}
+
+func TestOpenWithOptions(t *testing.T) {
+	title, err := charmap.Windows1251.NewEncoder().String("Война и мир")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"collection.info": "Test collection\n",
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		files[name+".inp"] = "Толстой,Лев:\x04prose:\x04" + title + "\x04\x04\x04" + name + "\x04100\x041\x04\x04fb2\x042010-01-02\x04ru\x04\x04\n" +
+			"broken line\n"
+	}
+	path := writeTestInpx(t, files)
+
+	var (
+		errs     int
+		progress []int
+	)
+	index, err := Open(path,
+		WithEncoding(charmap.Windows1251),
+		WithConcurrency(2),
+		WithErrorHandler(func(err error) { errs++ }),
+		WithProgress(func(done, total int) {
+			if total != 3 {
+				t.Errorf("unexpected total: %d", total)
+			}
+			progress = append(progress, done)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs != 3 {
+		t.Fatalf("expected 3 errors, got %d", errs)
+	}
+	if !reflect.DeepEqual(progress, []int{1, 2, 3}) {
+		t.Fatalf("unexpected progress: %v", progress)
+	}
+	if len(index.Archives) != 3 {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	for name, books := range index.Archives {
+		if len(books) != 1 || books[0].Title != "Война и мир" || books[0].File.Name != name {
+			t.Fatalf("unexpected books: %+v", books)
+		}
+	}
+}
+
+func TestPreserveRaw(t *testing.T) {
+	line := "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04"
+	path := writeTestInpx(t, map[string]string{"a.inp": line + "\r\n"})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if raw := index.Archives["a"][0].Raw; raw != nil {
+		t.Fatalf("raw line is preserved by default: %q", raw)
+	}
+	index, err = Open(path, WithPreserveRaw())
+	if err != nil {
+		t.Fatal(err)
+	} else if raw := string(index.Archives["a"][0].Raw); raw != line {
+		t.Fatalf("unexpected raw line: %q", raw)
+	}
+}
+
+func TestNonUTF8Names(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"collection.info": "Test collection\n",
+		"\xe9dition.inp":  "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if books := index.Archives["\u00e9dition"]; len(books) != 1 || books[0].File.Archive != "\u00e9dition" {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	info, err := InpxInfo(path)
+	if err != nil {
+		t.Fatal(err)
+	} else if info.Archives["\u00e9dition"] != 1 {
+		t.Fatalf("unexpected archives: %v", info.Archives)
+	}
+}
+
+func TestTruncatedLineHandler(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp": "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\nAuthor:\x04sf:\x04Cut\n",
+	})
+	var (
+		lines []string
+		errs  int
+	)
+	index, err := Open(path,
+		WithErrorHandler(func(err error) { errs++ }),
+		WithTruncatedLineHandler(func(archive string, lineNum int, raw []byte) {
+			lines = append(lines, fmt.Sprintf("%s:%d:%q", archive, lineNum, raw))
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.TotalBooks() != 1 || errs != 0 {
+		t.Fatalf("unexpected result: %d books, %d errors", index.TotalBooks(), errs)
+	}
+	if exp := []string{`a:2:"Author:\x04sf:\x04Cut"`}; !reflect.DeepEqual(lines, exp) {
+		t.Fatalf("unexpected truncated lines: %q", lines)
+	}
+}
+
+func TestLoadFields(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp": "Doe,John:\x04sf:\x04Title\x04Series\x041\x041\x04100\x0442\x04\x04fb2\x042010-01-02\x04en\x045\x04\x04\n",
+	})
+	index, err := Open(path, WithLoadFields(FieldTitle, FieldLibId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := Book{Title: "Title", LibId: 42, LibRate: -1, File: File{Dir: filepath.Dir(path), Archive: "a"}}
+	if books := index.Archives["a"]; len(books) != 1 || !reflect.DeepEqual(books[0], exp) {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+}
+
+func BenchmarkLoadFields(b *testing.B) {
+	idx := makeTestIndex(100000)
+	for _, books := range idx.Archives {
+		for i := range books {
+			books[i].Authors = []Author{newAuthor([]string{"Doe", "John", "J."})}
+			books[i].Genres = []string{"sf", "sf_fantasy"}
+			books[i].Series = "Series " + books[i].File.Name
+		}
+	}
+	path := filepath.Join(b.TempDir(), "test.inpx")
+	if err := idx.Save(path); err != nil {
+		b.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		opts []Option
+	}{
+		{"all", nil},
+		{"title-libid-lang", []Option{WithLoadFields(FieldTitle, FieldLibId, FieldLang)}},
+	} {
+		b.Run(c.name, func(b *testing.B) {
+			var heap uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				index, err := Open(path, c.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				runtime.KeepAlive(index)
+				heap += after.HeapAlloc - before.HeapAlloc
+			}
+			b.ReportMetric(float64(heap)/float64(b.N), "heap-bytes/op")
+		})
+	}
+}
+
+func TestSplitAuthors(t *testing.T) {
+	for _, c := range []struct {
+		list string
+		exp  []string
+	}{
+		{"Author1:Author2:", []string{"Author1", "Author2"}},
+		{"Author1::Author3", []string{"Author1", "", "Author3"}},
+		{"Author1", []string{"Author1"}},
+		{"Doe,John: Roe,Jane:", []string{"Doe,John", " Roe,Jane"}},
+		{"", nil},
+	} {
+		if got := splitAuthors(c.list); !reflect.DeepEqual(got, c.exp) {
+			t.Errorf("splitAuthors(%q) = %q, expected %q", c.list, got, c.exp)
+		}
+	}
+	path := writeTestInpx(t, map[string]string{"a.inp": "Doe,John: Roe,Jane:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authors := index.Archives["a"][0].Authors
+	if len(authors) != 2 || authors[0].LastName != "Doe" || authors[1].LastName != "Roe" || authors[1].FirstName != "Jane" {
+		t.Fatalf("unexpected authors: %+v", authors)
+	}
+}
+
+func TestBookString(t *testing.T) {
+	b := Book{
+		LibId:     42,
+		Title:     "Title",
+		Authors:   []Author{newAuthor([]string{"Doe", "John"}), newAuthor([]string{"Roe", "Jane"})},
+		Series:    "Series",
+		SeriesNum: 3,
+		Lang:      "en",
+		File:      File{Name: "42", Ext: "fb2"},
+	}
+	if s := b.String(); s != "[42] Title / John Doe, Jane Roe (Series #3) [en] fb2" {
+		t.Fatalf("unexpected string: %q", s)
+	}
+	b.Series, b.File = "", File{}
+	if s := b.String(); s != "[42] Title / John Doe, Jane Roe [en]" {
+		t.Fatalf("unexpected string: %q", s)
+	}
+}
+
+func TestSplitName(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		exp  []string
+	}{
+		{"Толстой,Лев,Николаевич", []string{"Толстой", "Лев", "Николаевич"}},
+		{"Jr., Smith", []string{"Jr., Smith"}},
+		{"Smith,John Jr., Esq.", []string{"Smith", "John Jr., Esq."}},
+		{"Doe,John,", []string{"Doe", "John"}},
+		{"Doe,,John", []string{"Doe", "John"}},
+		{"Doe", []string{"Doe"}},
+		{"", nil},
+	} {
+		if got := splitName(c.name); !reflect.DeepEqual(got, c.exp) {
+			t.Errorf("splitName(%q) = %q, expected %q", c.name, got, c.exp)
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	exp := time.Date(2010, 3, 4, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2010-03-04", "04.03.2010", "2010/03/04"} {
+		d, err := parseDate(s, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		} else if !d.Equal(exp) {
+			t.Fatalf("parseDate(%q) = %v", s, d)
+		}
+	}
+	if d, err := parseDate("2010", time.UTC); err != nil || d.Year() != 2010 {
+		t.Fatalf("parseDate(year) = %v, %v", d, err)
+	}
+	if _, err := parseDate("yesterday", time.UTC); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestFieldNames(t *testing.T) {
+	if len(FieldName) != len(FieldByName) {
+		t.Fatalf("maps have different sizes: %d vs %d", len(FieldName), len(FieldByName))
+	}
+	for name, f := range FieldByName {
+		if FieldName[f] != name {
+			t.Fatalf("unexpected name for %s: %q", name, FieldName[f])
+		}
+	}
+	_, err := fieldsToBook([][]byte{[]byte("Title"), []byte("x")}, []int{FieldTitle, FieldLibId}, time.UTC)
+	if err == nil || !strings.HasPrefix(err.Error(), "LIBID: ") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDetectWindows1251(t *testing.T) {
+	line := "Толстой,Лев:\x04prose:\x04Война и мир\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04ru\x04\x04\n"
+	cp, err := charmap.Windows1251.NewEncoder().String(line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeTestInpx(t, map[string]string{"a.inp": cp + line})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["a"]
+	if len(books) != 2 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	for _, b := range books {
+		if b.Title != "Война и мир" || b.Authors[0].LastName != "Толстой" {
+			t.Fatalf("unexpected book: %v", b)
+		}
+	}
+	index, err = Open(path, WithEncoding(encoding.Nop))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := index.Archives["a"][0]; b.Title == "Война и мир" {
+		t.Fatalf("unexpected decoding with Nop encoding: %v", b)
+	}
+}
+
+func TestOpenContext(t *testing.T) {
+	files := map[string]string{}
+	for _, name := range []string{"a", "b", "c"} {
+		files[name+".inp"] = strings.Repeat("Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n", 2*ctxCheckBooks)
+	}
+	path := writeTestInpx(t, files)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := OpenContext(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	_, err := OpenContext(ctx, path, WithProgress(func(done, total int) {
+		if done == 1 {
+			cancel()
+		}
+	}))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	index, err := OpenContext(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	} else if n := index.TotalBooks(); n != 6*ctxCheckBooks {
+		t.Fatalf("unexpected books count: %d", n)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	good := "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	path := writeTestInpx(t, map[string]string{
+		"b.inp": good + "bad b\n",
+		"a.inp": "bad a1\n" + good + "bad a3\n",
+	})
+	index, err := Open(path, WithConcurrency(2), WithErrorHandler(func(error) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range index.ParseErrors {
+		if !errors.Is(e, ErrTruncated) {
+			t.Fatalf("unexpected error: %v", e)
+		}
+		got = append(got, fmt.Sprintf("%s:%d:%s", e.Archive, e.Line, e.Raw))
+	}
+	if exp := []string{"a:1:bad a1", "a:3:bad a3", "b:2:bad b"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected errors: %q", got)
+	}
+	index, err = Open(path, WithLazyLoad(), WithErrorHandler(func(error) {}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	if len(index.ParseErrors) != 0 {
+		t.Fatalf("unexpected errors: %v", index.ParseErrors)
+	}
+	if _, err = index.Archive("b"); err != nil {
+		t.Fatal(err)
+	} else if len(index.ParseErrors) != 1 || index.ParseErrors[0].Line != 2 {
+		t.Fatalf("unexpected errors: %v", index.ParseErrors)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	good := "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	path := writeTestInpx(t, map[string]string{"a.inp": good + "bad\n" + good})
+	_, err := Open(path, WithStrict(), WithErrorHandler(func(err error) {
+		t.Errorf("unexpected call of error handler: %v", err)
+	}))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Archive != "a" || perr.Line != 2 || string(perr.Raw) != "bad" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path = writeTestInpx(t, map[string]string{"a.inp": good + good})
+	if index, err := Open(path, WithStrict()); err != nil {
+		t.Fatal(err)
+	} else if n := index.TotalBooks(); n != 2 {
+		t.Fatalf("unexpected books count: %d", n)
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"structure.info": "TITLE;KEYWORDS;",
+		"a.inp":          "A\x04magic, dragons,,  elves \x04\nB\x04\x04\n",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["a"]
+	if len(books) != 2 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	if exp := []string{"magic", "dragons", "elves"}; !reflect.DeepEqual(books[0].Keywords, exp) {
+		t.Fatalf("unexpected keywords: %q", books[0].Keywords)
+	}
+	if books[1].Keywords != nil {
+		t.Fatalf("unexpected keywords: %q", books[1].Keywords)
+	}
+	if line := EncodeBook(books[0], index.Structure); string(line) != "A\x04magic,dragons,elves\n" {
+		t.Fatalf("unexpected line: %q", line)
+	}
+}
+
+func TestDateLocation(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"fb2-000.inp": "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x042010-03-04\x04\x04\x04\n",
+	})
+	loc := time.FixedZone("Moscow", 3*3600)
+	index, err := Open(path, WithDateLocation(loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := index.Archives["fb2-000"][0].Date
+	if exp := time.Date(2010, 3, 4, 0, 0, 0, 0, loc); !d.Equal(exp) {
+		t.Fatalf("unexpected date: %v", d)
+	}
+	if utc := time.Date(2010, 3, 4, 0, 0, 0, 0, time.UTC); d.Equal(utc) {
+		t.Fatalf("date is not affected by location: %v", d)
+	}
+	index, err = Open(path, WithDateLocation(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d = index.Archives["fb2-000"][0].Date
+	if exp := time.Date(2010, 3, 4, 0, 0, 0, 0, time.UTC); d != exp {
+		t.Fatalf("unexpected date with nil location: %v", d)
+	}
+}
+
+func TestLazyLoad(t *testing.T) {
+	files := map[string]string{}
+	for _, name := range []string{"fb2-000", "fb2-001", "fb2-002"} {
+		files[name+".inp"] = "Author:\x04sf:\x04" + name + "\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	}
+	path := writeTestInpx(t, files)
+	index, err := Open(path, WithLazyLoad())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	if len(index.Archives) != 0 {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	if names := index.UnloadedArchives(); !reflect.DeepEqual(names, []string{"fb2-000", "fb2-001", "fb2-002"}) {
+		t.Fatalf("unexpected unloaded archives: %v", names)
+	}
+	books, err := index.Archive("fb2-001")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(books) != 1 || books[0].Title != "fb2-001" || books[0].File.Dir != filepath.Dir(path) {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	if !reflect.DeepEqual(index.Archives["fb2-001"], books) || len(index.Archives) != 1 {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	if names := index.UnloadedArchives(); !reflect.DeepEqual(names, []string{"fb2-000", "fb2-002"}) {
+		t.Fatalf("unexpected unloaded archives: %v", names)
+	}
+	if books2, err := index.Archive("fb2-001"); err != nil || !reflect.DeepEqual(books2, books) {
+		t.Fatalf("unexpected books: %v, %v", books2, err)
+	}
+	if books, err := index.Archive("fb2-002"); err != nil || len(books) != 1 || books[0].Title != "fb2-002" {
+		t.Fatalf("unexpected books: %v, %v", books, err)
+	}
+	if _, err = index.Archive("fb2-003"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = index.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if books, err = index.Archive("fb2-001"); err != nil || len(books) != 1 {
+		t.Fatalf("unexpected result after close: %v, %v", books, err)
+	}
+	if _, err = index.Archive("fb2-000"); err == nil {
+		t.Fatal("expected an error after close")
+	}
+}
+
+func TestLazyLoadAll(t *testing.T) {
+	files := map[string]string{}
+	for _, name := range []string{"fb2-000", "fb2-001", "fb2-002"} {
+		files[name+".inp"] = "Author:\x04sf:\x04" + name + "\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	}
+	files["empty.inp"] = ""
+	path := writeTestInpx(t, files)
+	index, err := Open(path, WithLazyLoad())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer index.Close()
+	if n := index.Compact(); n != 0 || len(index.UnloadedArchives()) != 4 {
+		t.Fatalf("compact removed unloaded archives: %d, %v", n, index.UnloadedArchives())
+	}
+	if books := index.AllBooks(); len(books) != 0 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	out := filepath.Join(filepath.Dir(path), "out.inpx")
+	if err = index.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	if names := index.UnloadedArchives(); len(names) != 0 {
+		t.Fatalf("unexpected unloaded archives: %v", names)
+	}
+	if n := index.TotalBooks(); n != 3 {
+		t.Fatalf("unexpected total books: %d", n)
+	}
+	if n := index.Compact(); n != 0 || len(index.Archives) != 3 {
+		t.Fatalf("unexpected compact result: %d, %v", n, index.Archives)
+	}
+	index2, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index2.AllBooks()
+	if len(books) != 3 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	for i, name := range []string{"fb2-000", "fb2-001", "fb2-002"} {
+		if books[i].Title != name {
+			t.Fatalf("unexpected book %d: %v", i, books[i])
+		}
+	}
+}
+
+func TestIncludeArchives(t *testing.T) {
+	files := map[string]string{}
+	for _, name := range []string{"fb2-000", "fb2-001", "fb2-002", "usr-000"} {
+		files[name+".inp"] = "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	}
+	path := writeTestInpx(t, files)
+	index, err := Open(path,
+		WithIncludeArchives("fb2-*", "usr-000"),
+		WithExcludeArchives("fb2-001"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := index.archiveNames()
+	if exp := []string{"fb2-000", "fb2-002", "usr-000"}; !reflect.DeepEqual(names, exp) {
+		t.Fatalf("unexpected archives: %v", names)
+	}
+}
+
+func TestBookClone(t *testing.T) {
+	b := Book{
+		Title:   "Title",
+		Authors: []Author{newAuthor([]string{"Doe", "John"})},
+		Genres:  []string{"sf"},
+	}
+	orig := Book{
+		Title:   "Title",
+		Authors: []Author{newAuthor([]string{"Doe", "John"})},
+		Genres:  []string{"sf"},
+	}
+	c := b.Clone()
+	if !reflect.DeepEqual(b, c) {
+		t.Fatalf("clone differs: %+v", c)
+	}
+	c.Authors[0].Name[0] = "Roe"
+	c.Authors[0].LastName = "Roe"
+	c.Authors = append(c.Authors, newAuthor([]string{"Other"}))
+	c.Genres[0] = "det"
+	if !reflect.DeepEqual(b, orig) {
+		t.Fatalf("original book changed: %+v", b)
+	}
+}
+
+func TestBookEqual(t *testing.T) {
+	b := Book{
+		LibId:   1,
+		Title:   "Title",
+		Authors: []Author{newAuthor([]string{"Doe", "John"})},
+		Genres:  []string{"sf"},
+		Date:    testDate,
+		File:    File{Name: "1", Ext: "fb2", Dir: "a", Archive: "fb2-001"},
+	}
+	o := b.Clone()
+	o.File.Dir, o.File.Archive = "b", "fb2-002"
+	o.Date = testDate.In(time.FixedZone("test", 3600))
+	if !b.ContentEqual(o) || !b.MetaEqual(o) {
+		t.Fatal("books should be equal")
+	}
+	for _, fn := range []func(*Book){
+		func(o *Book) { o.LibRate = 5 },
+		func(o *Book) { o.ISBN = "978-3-16-148410-0" },
+		func(o *Book) { o.Keywords = []string{"magic"} },
+		func(o *Book) { o.Deleted = true },
+		func(o *Book) { o.File.Ext = "epub" },
+	} {
+		o2 := o.Clone()
+		fn(&o2)
+		if !b.ContentEqual(o2) || b.MetaEqual(o2) {
+			t.Fatalf("books should be equal only by content: %+v", o2)
+		}
+	}
+	o.Deleted = true
+	o.Authors[0] = Author{LastName: "Doe", FirstName: "Jim"}
+	if b.ContentEqual(o) {
+		t.Fatal("books should not be equal")
+	}
+}
+
+// exampleInpx is a small inpx file with a single book.
+var exampleInpx = []byte{
+	0x50, 0x4b, 0x03, 0x04, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0f, 0x00, 0x00, 0x00, 0x63, 0x6f,
+	0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x69, 0x6e, 0x66, 0x6f, 0x00, 0x10, 0x00,
+	0xef, 0xff, 0x45, 0x78, 0x61, 0x6d, 0x70, 0x6c, 0x65, 0x20, 0x6c, 0x69, 0x62, 0x72, 0x61, 0x72,
+	0x79, 0x0a, 0x03, 0x00, 0x50, 0x4b, 0x07, 0x08, 0xb4, 0xcc, 0x7c, 0x77, 0x17, 0x00, 0x00, 0x00,
+	0x10, 0x00, 0x00, 0x00, 0x50, 0x4b, 0x03, 0x04, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0c, 0x00,
+	0x00, 0x00, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x69, 0x6e, 0x66, 0x6f, 0x00, 0x09,
+	0x00, 0xf6, 0xff, 0x32, 0x30, 0x32, 0x30, 0x30, 0x31, 0x30, 0x31, 0x0a, 0x03, 0x00, 0x50, 0x4b,
+	0x07, 0x08, 0x2f, 0x30, 0xd0, 0xb3, 0x10, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x50, 0x4b,
+	0x03, 0x04, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x00, 0x00, 0x66, 0x62, 0x32, 0x2d,
+	0x30, 0x30, 0x31, 0x2e, 0x69, 0x6e, 0x70, 0x00, 0x76, 0x00, 0x89, 0xff, 0xd0, 0xa2, 0xd0, 0xbe,
+	0xd0, 0xbb, 0xd1, 0x81, 0xd1, 0x82, 0xd0, 0xbe, 0xd0, 0xb9, 0x2c, 0xd0, 0x9b, 0xd0, 0xb5, 0xd0,
+	0xb2, 0x2c, 0xd0, 0x9d, 0xd0, 0xb8, 0xd0, 0xba, 0xd0, 0xbe, 0xd0, 0xbb, 0xd0, 0xb0, 0xd0, 0xb5,
+	0xd0, 0xb2, 0xd0, 0xb8, 0xd1, 0x87, 0x3a, 0x04, 0x70, 0x72, 0x6f, 0x73, 0x65, 0x5f, 0x72, 0x75,
+	0x73, 0x5f, 0x63, 0x6c, 0x61, 0x73, 0x73, 0x69, 0x63, 0x3a, 0x04, 0xd0, 0x92, 0xd0, 0xbe, 0xd0,
+	0xb9, 0xd0, 0xbd, 0xd0, 0xb0, 0x20, 0xd0, 0xb8, 0x20, 0xd0, 0xbc, 0xd0, 0xb8, 0xd1, 0x80, 0x04,
+	0x04, 0x04, 0x31, 0x04, 0x31, 0x30, 0x30, 0x30, 0x04, 0x31, 0x04, 0x30, 0x04, 0x66, 0x62, 0x32,
+	0x04, 0x32, 0x30, 0x31, 0x30, 0x2d, 0x30, 0x31, 0x2d, 0x30, 0x32, 0x04, 0x72, 0x75, 0x04, 0x04,
+	0x04, 0x0a, 0x03, 0x00, 0x50, 0x4b, 0x07, 0x08, 0x3f, 0xa7, 0xf3, 0xc7, 0x7d, 0x00, 0x00, 0x00,
+	0x76, 0x00, 0x00, 0x00, 0x50, 0x4b, 0x01, 0x02, 0x14, 0x00, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0xb4, 0xcc, 0x7c, 0x77, 0x17, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00,
+	0x0f, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x69, 0x6e, 0x66,
+	0x6f, 0x50, 0x4b, 0x01, 0x02, 0x14, 0x00, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x2f, 0x30, 0xd0, 0xb3, 0x10, 0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x0c, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x54, 0x00, 0x00, 0x00, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x2e, 0x69, 0x6e, 0x66, 0x6f, 0x50, 0x4b, 0x01, 0x02, 0x14,
+	0x00, 0x14, 0x00, 0x08, 0x00, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x3f, 0xa7, 0xf3, 0xc7, 0x7d,
+	0x00, 0x00, 0x00, 0x76, 0x00, 0x00, 0x00, 0x0b, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00, 0x00, 0x9e, 0x00, 0x00, 0x00, 0x66, 0x62, 0x32, 0x2d, 0x30, 0x30, 0x31,
+	0x2e, 0x69, 0x6e, 0x70, 0x50, 0x4b, 0x05, 0x06, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x03, 0x00,
+	0xb0, 0x00, 0x00, 0x00, 0x54, 0x01, 0x00, 0x00, 0x00, 0x00,
+}
+
+func ExampleOpenBytes() {
+	index, err := OpenBytes(exampleInpx, DefaultStructure)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(index.Name)
+	for _, b := range index.AllBooks() {
+		fmt.Println(b)
+	}
+	// Output:
+	// Example library
+	// [1] Война и мир / Лев Николаевич Толстой [ru] fb2
+}
+
+func TestMultiPartFile(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"structure.info": "TITLE;FILE;EXT;FOLDER;",
+		"books.inp": "Split\x041\x04fb2\x04a001.zip,a002.zip\x04\n" +
+			"Single\x042\x04fb2\x04a002.zip\x04\n" +
+			"Default\x043\x04fb2\x04\x04\n",
+	})
+	dir := filepath.Dir(path)
+	writeTestZip(t, filepath.Join(dir, "a001.zip"), map[string]string{"1.fb2": "Hello, "})
+	writeTestZip(t, filepath.Join(dir, "a002.zip"), map[string]string{"1.fb2": "world", "2.fb2": "single"})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["books"]
+	if len(books) != 3 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	if fr := books[0].File; fr.Archive != "a001.zip" || !reflect.DeepEqual(fr.AdditionalArchives, []string{"a002.zip"}) {
+		t.Fatalf("unexpected file: %+v", fr)
+	}
+	if fr := books[1].File; fr.Archive != "a002.zip" || fr.AdditionalArchives != nil {
+		t.Fatalf("unexpected file: %+v", fr)
+	}
+	if fr := books[2].File; fr.Archive != "books" || fr.AdditionalArchives != nil {
+		t.Fatalf("unexpected file: %+v", fr)
+	}
+	for i, exp := range []string{"Hello, world", "single"} {
+		rc, err := books[i].File.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		} else if err = rc.Close(); err != nil {
+			t.Fatal(err)
+		} else if string(data) != exp {
+			t.Fatalf("unexpected content: %q", data)
+		}
+	}
+	pool := NewArchivePool(4)
+	defer pool.Close()
+	rc, err := books[0].File.OpenVia(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	} else if err = rc.Close(); err != nil {
+		t.Fatal(err)
+	} else if string(data) != "Hello, world" {
+		t.Fatalf("unexpected content via pool: %q", data)
+	}
+	if ok, err := books[0].File.Exists(); err != nil || !ok {
+		t.Fatalf("file should exist: %v", err)
+	}
+	if _, _, err = books[0].File.OpenCompressed(); !errors.Is(err, ErrSplitFile) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = CopyBook(books[0].File, filepath.Join(dir, "copy.zip")); !errors.Is(err, ErrSplitFile) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if missing := missingFiles(books[:2]); len(missing) != 0 {
+		t.Fatalf("unexpected missing files: %v", missing)
+	}
+	broken := books[0]
+	broken.File.Name = "2"
+	if ok, err := broken.File.Exists(); err != nil || ok {
+		t.Fatalf("file should not exist: %v", err)
+	}
+	if missing := missingFiles([]Book{books[1], broken}); len(missing) != 1 || missing[0].File.Name != "2" {
+		t.Fatalf("unexpected missing files: %v", missing)
+	}
+	fr := books[0].File
+	fr.AdditionalArchives = []string{"a003.zip"}
+	if _, err = fr.Open(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err = fr.OpenVia(pool); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	line := EncodeBook(books[0], []int{FieldTitle, FieldFolder})
+	if string(line) != "Split\x04a001.zip,a002.zip\n" {
+		t.Fatalf("unexpected line: %q", line)
+	}
+}
+
+func TestOpenFast(t *testing.T) {
+	files := map[string]string{}
+	var lines string
+	for _, name := range []string{"1", "2", "3"} {
+		files[name+".fb2"] = "book " + name
+		lines += "Author:\x04sf:\x04Title\x04\x04\x04" + name + "\x04\x04" + name + "\x04\x04fb2\x04\x04\x04\x04\n"
+	}
+	path := writeTestInpx(t, map[string]string{"fb2-001.inp": lines})
+	writeTestZip(t, filepath.Join(filepath.Dir(path), "fb2-001.zip"), files)
+	index, err := Open(path, WithFileOffsets())
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["fb2-001"]
+	if len(books) != 3 {
+		t.Fatalf("unexpected books: %v", books)
+	}
+	for _, b := range books {
+		if b.File.Offset == 0 {
+			t.Fatalf("offset is not set: %+v", b.File)
+		}
+		rc, err := b.File.OpenFast()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if string(data) != files[b.File.Name+".fb2"] {
+			t.Fatalf("unexpected content: %q", data)
+		}
+	}
+}
+
+func TestFieldsBOM(t *testing.T) {
+	line := []byte("\ufeffTitle\x04\ufeffDoe,John:\x0442")
+	b, err := fieldsToBook(bytes.Split(line, []byte{0x04}), []int{FieldTitle, FieldAuthor, FieldLibId}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "Title" || b.LibId != 42 || b.Authors[0].LastName != "Doe" {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+}
+
+func TestFieldsTrailingColon(t *testing.T) {
+	line := []byte("Note:\x04sf_history:prose_classic:\x04Doe,John:\x04123:\x04Series:")
+	b, err := fieldsToBook(bytes.Split(line, []byte{0x04}), []int{FieldTitle, FieldGenre, FieldAuthor, FieldFileName, FieldSeries}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "Note:" || b.Series != "Series:" {
+		t.Fatalf("colon is stripped: %q, %q", b.Title, b.Series)
+	}
+	if !reflect.DeepEqual(b.Genres, []string{"sf_history", "prose_classic"}) {
+		t.Fatalf("unexpected genres: %q", b.Genres)
+	}
+	if b.File.Name != "123" || len(b.Authors) != 1 || b.Authors[0].FirstName != "John" {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+}
+
+func TestFilePaths(t *testing.T) {
+	for _, c := range []struct {
+		file     File
+		abs, rel string
+	}{
+		{File{Dir: "lib", Archive: "fb2-001", Name: "1", Ext: "fb2"}, filepath.Join("lib", "fb2-001.zip"), filepath.Join("fb2-001.zip", "1.fb2")},
+		{File{Dir: "lib", Archive: "fb2-002.zip", Name: "2", Ext: "epub"}, filepath.Join("lib", "fb2-002.zip"), filepath.Join("fb2-002.zip", "2.epub")},
+	} {
+		if abs := c.file.AbsolutePath(); abs != c.abs {
+			t.Errorf("unexpected absolute path: %q", abs)
+		}
+		if rel := c.file.RelativePath(); rel != c.rel {
+			t.Errorf("unexpected relative path: %q", rel)
+		}
+	}
+}
+
+func TestFieldsControlChars(t *testing.T) {
+	line := []byte("T\x00i\x00t\x00l\x00e\x00\x04Doe,\x01John:\x04\x0042\x00")
+	b, err := fieldsToBook(bytes.Split(line, []byte{0x04}), []int{FieldTitle, FieldAuthor, FieldLibId}, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Title != "Title" || b.LibId != 42 || b.Authors[0].FirstName != "John" {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+	if s := sanitizeField("a\tb\x7fc"); s != "a\tbc" {
+		t.Fatalf("unexpected field: %q", s)
+	}
+}
+
+func TestArchiveInfo(t *testing.T) {
+	line := "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n"
+	path := writeTestInpx(t, map[string]string{"a.inp": line, "b.inp": line})
+	zpath := filepath.Join(filepath.Dir(path), "a.zip")
+	writeTestZip(t, zpath, map[string]string{"1.fb2": "book"})
+	fi, err := os.Stat(zpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, ok := index.ArchiveInfo("a")
+	if !ok || a.Name != "a" || len(a.Books) != 1 || a.ZipSize != fi.Size() || !a.ModTime.Equal(fi.ModTime()) {
+		t.Fatalf("unexpected archive info: %+v", a)
+	}
+	b, ok := index.ArchiveInfo("b")
+	if !ok || len(b.Books) != 1 || b.ZipSize != 0 || !b.ModTime.IsZero() {
+		t.Fatalf("unexpected archive info: %+v", b)
+	}
+	if _, ok = index.ArchiveInfo("c"); ok {
+		t.Fatal("unexpected archive")
+	}
+}
+
+func TestArchiveWithZipExt(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"archive.zip.inp": "Author:\x04sf:\x04Title\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n",
+	})
+	writeTestZip(t, filepath.Join(filepath.Dir(path), "archive.zip"), map[string]string{"1.fb2": "book"})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["archive.zip"]
+	if len(books) != 1 {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	if ok, err := books[0].File.Exists(); err != nil || !ok {
+		t.Fatalf("file does not exist: %v", err)
+	}
+	rc, err := books[0].File.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+}
+
+var roundTripBooks = []Book{
+	{
+		Authors:   []Author{newAuthor([]string{"Толстой", "Лев", "Николаевич"})},
+		Genres:    []string{"prose_classic", "prose_rus_classic"},
+		Title:     "Война и мир",
+		Series:    "Эпопея",
+		SeriesNum: 1,
+		File:      File{Name: "1234", Ext: "fb2", Size: 100500},
+		LibId:     1234,
+		Date:      time.Date(2010, 1, 2, 0, 0, 0, 0, time.UTC),
+		Lang:      "ru",
+		LibRate:   5,
+	},
+	{
+		Authors: []Author{newAuthor([]string{"Doe", "John"}), newAuthor([]string{"Roe", "Jane", "Q."})},
+		Genres:  []string{"sf"},
+		Title:   "Multiple authors, no series",
+		File:    File{Name: "book", Ext: "epub", Size: 1},
+		LibId:   1,
+		Deleted: true,
+		Lang:    "en",
+		LibRate: 0,
+	},
+	{
+		Authors: []Author{newAuthor([]string{"Anonymous"})},
+		Title:   "Zero date, no genres",
+		LibId:   2,
+		LibRate: -1,
+	},
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, b := range roundTripBooks {
+		line := EncodeBook(b, DefaultStructure)
+		line = bytes.TrimSuffix(line, []byte{'\n'})
+		got, err := fieldsToBook(bytes.Split(line, []byte{0x04}), DefaultStructure, time.UTC)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(b, got) {
+			t.Errorf("book changed after round trip:\n%#v\nvs\n%#v", b, got)
+		}
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	data, err := MakeTestINPX(roundTripBooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenReaderAt(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Name != "Test collection" || index.Version != "1" || len(index.Archives) != 1 {
+		t.Fatalf("unexpected index: %+v", index)
+	}
+	books := index.Archives["books"]
+	if len(books) != len(roundTripBooks) {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+	for i, b := range roundTripBooks {
+		b.File.Archive = "books"
+		if !reflect.DeepEqual(b, books[i]) {
+			t.Errorf("book changed after round trip:\n%#v\nvs\n%#v", b, books[i])
+		}
+	}
+}
+
+type errCloser struct{ err error }
+
+func (c errCloser) Close() error { return c.err }
+
+func TestMultiReadCloser(t *testing.T) {
+	mc := multiReadCloser{closers: []io.Closer{errCloser{}, errCloser{}}}
+	if err := mc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	err1, err2 := errors.New("err1"), errors.New("err2")
+	mc = multiReadCloser{closers: []io.Closer{errCloser{err1}, errCloser{}, errCloser{err2}}}
+	err := mc.Close()
+	if err == nil || err.Error() != "close errors: err1; err2" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(err, err2) {
+		t.Fatal("error should wrap err2")
+	}
+}
+
+func TestAuthorText(t *testing.T) {
+	type doc struct {
+		XMLName xml.Name `xml:"book" json:"-"`
+		Authors []Author `xml:"author" json:"authors"`
+	}
+	in := doc{Authors: []Author{
+		newAuthor([]string{"Толстой", "Лев", "Николаевич"}),
+		newAuthor([]string{"Doe", "John"}),
+		newAuthor([]string{"Homer"}),
+	}}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `{"authors":["Лев Николаевич Толстой","John Doe","Homer"]}`; string(data) != exp {
+		t.Fatalf("unexpected json: %s", data)
+	}
+	var out doc
+	if err = json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in.Authors, out.Authors) {
+		t.Fatalf("unexpected authors: %+v", out.Authors)
+	}
+
+	data, err = xml.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := `<book><author>Лев Николаевич Толстой</author><author>John Doe</author><author>Homer</author></book>`; string(data) != exp {
+		t.Fatalf("unexpected xml: %s", data)
+	}
+	out = doc{}
+	if err = xml.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in.Authors, out.Authors) {
+		t.Fatalf("unexpected authors: %+v", out.Authors)
+	}
+}
+
+// benchSizes are numbers of books in indexes used by benchmarks.
+var benchSizes = []int{100, 1000, 10000}
+
+// benchIndexes generates an inpx file for each of benchSizes and calls fn with its index.
+func benchIndexes(b *testing.B, fn func(b *testing.B, data []byte, idx *Index)) {
+	for _, n := range benchSizes {
+		data, err := MakeTestINPX(makeTestIndex(n).AllBooks())
+		if err != nil {
+			b.Fatal(err)
+		}
+		idx, err := OpenBytes(data, DefaultStructure)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			fn(b, data, idx)
+		})
+	}
+}
+
+func BenchmarkOpen(b *testing.B) {
+	benchIndexes(b, func(b *testing.B, data []byte, _ *Index) {
+		for i := 0; i < b.N; i++ {
+			if _, err := OpenBytes(data, DefaultStructure); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkFilter(b *testing.B) {
+	benchIndexes(b, func(b *testing.B, _ []byte, idx *Index) {
+		for i := 0; i < b.N; i++ {
+			idx.Filter(func(b Book) bool { return !b.Deleted })
+		}
+	})
+}
+
+func BenchmarkAllBooks(b *testing.B) {
+	benchIndexes(b, func(b *testing.B, _ []byte, idx *Index) {
+		for i := 0; i < b.N; i++ {
+			idx.AllBooks()
+		}
+	})
+}
+
+func BenchmarkBuildLibIdIndex(b *testing.B) {
+	benchIndexes(b, func(b *testing.B, _ []byte, idx *Index) {
+		for i := 0; i < b.N; i++ {
+			idx.BuildLibIdIndex()
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package inpx
+
+import "strings"
+
+// NormalizeISBN converts ISBN-10 or ISBN-13 to ISBN-13 without dashes and spaces.
+// It returns an empty string if the ISBN has a wrong length or an invalid check digit.
+func NormalizeISBN(isbn string) string {
+	s := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ':
+			return -1
+		case 'x':
+			return 'X'
+		}
+		return r
+	}, isbn)
+	s = strings.TrimPrefix(s, "ISBN")
+	switch len(s) {
+	case 10:
+		sum := 0
+		for i, r := range s {
+			d := int(r - '0')
+			if r == 'X' && i == 9 {
+				d = 10
+			} else if r < '0' || r > '9' {
+				return ""
+			}
+			sum += (10 - i) * d
+		}
+		if sum%11 != 0 {
+			return ""
+		}
+		s = "978" + s[:9]
+		return s + string(rune('0'+isbn13Check(s)))
+	case 13:
+		for _, r := range s {
+			if r < '0' || r > '9' {
+				return ""
+			}
+		}
+		if int(s[12]-'0') != isbn13Check(s[:12]) {
+			return ""
+		}
+		return s
+	}
+	return ""
+}
+
+// isbn13Check computes the check digit for the first 12 digits of ISBN-13.
+func isbn13Check(s string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(s[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
+
+// FindByISBN returns the first book with a given ISBN, in the same order as Index.AllBooks.
+// Both ISBN-10 and ISBN-13 are accepted and matched with each other (see NormalizeISBN).
+func (idx *Index) FindByISBN(isbn string) (Book, bool) {
+	isbn = NormalizeISBN(isbn)
+	if isbn == "" {
+		return Book{}, false
+	}
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if b.ISBN != "" && NormalizeISBN(b.ISBN) == isbn {
+				return b, true
+			}
+		}
+	}
+	return Book{}, false
+}
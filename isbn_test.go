@@ -0,0 +1,44 @@
+package inpx
+
+import "testing"
+
+func TestNormalizeISBN(t *testing.T) {
+	for _, c := range []struct {
+		isbn string
+		exp  string
+	}{
+		{"978-0-306-40615-7", "9780306406157"},
+		{"0-306-40615-2", "9780306406157"},
+		{"ISBN 0 8044 2957 x", "9780804429573"},
+		{"978-0-306-40615-8", ""},
+		{"0-306-40615-3", ""},
+		{"03064061X2", ""},
+		{"12345", ""},
+		{"", ""},
+	} {
+		if got := NormalizeISBN(c.isbn); got != c.exp {
+			t.Errorf("NormalizeISBN(%q) = %q, expected %q", c.isbn, got, c.exp)
+		}
+	}
+}
+
+func TestFindByISBN(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"structure.info": "TITLE;LIBID;ISBN;",
+		"a.inp":          "First\x041\x04\x04\nSecond\x042\x040-306-40615-2\x04\n",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, ok := index.FindByISBN("9780306406157")
+	if !ok || b.LibId != 2 || b.ISBN != "0-306-40615-2" {
+		t.Fatalf("unexpected book: %v, %v", b, ok)
+	}
+	if _, ok = index.FindByISBN("978-0-8044-2957-3"); ok {
+		t.Fatal("unexpected book")
+	}
+	if _, ok = index.FindByISBN("invalid"); ok {
+		t.Fatal("unexpected book")
+	}
+}
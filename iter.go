@@ -0,0 +1,48 @@
+//go:build go1.23
+
+package inpx
+
+import (
+	"io"
+	"iter"
+)
+
+// All returns an iterator over all books of the index, in the same order as AllBooks.
+// Unlike AllBooks, it does not copy books to a new slice.
+func (idx *Index) All() iter.Seq[Book] {
+	return func(yield func(Book) bool) {
+		for _, name := range idx.archiveNames() {
+			for _, b := range idx.Archives[name] {
+				if !yield(b) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// OpenIter returns an iterator that reads books from an inpx file one by one, without loading
+// the whole index (see IndexReader). The file is opened when the iteration starts and closed
+// when it ends. If the file cannot be read, the error is yielded and the iteration stops.
+func OpenIter(path string, opts ...Option) iter.Seq2[Book, error] {
+	return func(yield func(Book, error) bool) {
+		r, err := NewIndexReader(path, opts...)
+		if err != nil {
+			yield(Book{}, err)
+			return
+		}
+		defer r.Close()
+		for {
+			_, b, err := r.Next()
+			if err == io.EOF {
+				return
+			} else if err != nil {
+				yield(Book{}, err)
+				return
+			}
+			if !yield(b, nil) {
+				return
+			}
+		}
+	}
+}
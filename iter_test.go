@@ -0,0 +1,60 @@
+//go:build go1.23
+
+package inpx
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestIndexAll(t *testing.T) {
+	idx := makeTestIndex(100)
+	var got []Book
+	for b := range idx.All() {
+		got = append(got, b)
+	}
+	if !reflect.DeepEqual(got, idx.AllBooks()) {
+		t.Fatal("unexpected books")
+	}
+	n := 0
+	for range idx.All() {
+		if n++; n == 10 {
+			break
+		}
+	}
+	if n != 10 {
+		t.Fatalf("unexpected count: %d", n)
+	}
+}
+
+func TestOpenIter(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp":          "A1\nA2\n",
+		"b.inp":          "B1\n",
+		"structure.info": "TITLE",
+	})
+	var titles []string
+	for b, err := range OpenIter(path) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		titles = append(titles, b.File.Archive+"/"+b.Title)
+		if b.Title == "A2" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(titles, []string{"a/A1", "a/A2"}) {
+		t.Fatalf("unexpected books: %q", titles)
+	}
+	var errs int
+	for _, err := range OpenIter(filepath.Join(t.TempDir(), "missing.inpx")) {
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Fatalf("unexpected errors count: %d", errs)
+	}
+}
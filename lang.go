@@ -0,0 +1,130 @@
+package inpx
+
+import "strings"
+
+// langCodes maps three-letter (ISO 639-2/T and 639-2/B) codes and English language names
+// to ISO 639-1 codes.
+var langCodes = map[string]string{
+	"afr": "af", "afrikaans": "af",
+	"ara": "ar", "arabic": "ar",
+	"aze": "az", "azerbaijani": "az",
+	"bel": "be", "belarusian": "be",
+	"bul": "bg", "bulgarian": "bg",
+	"ben": "bn", "bengali": "bn",
+	"bos": "bs", "bosnian": "bs",
+	"cat": "ca", "catalan": "ca",
+	"ces": "cs", "cze": "cs", "czech": "cs",
+	"dan": "da", "danish": "da",
+	"deu": "de", "ger": "de", "german": "de",
+	"ell": "el", "gre": "el", "greek": "el",
+	"eng": "en", "english": "en",
+	"epo": "eo", "esperanto": "eo",
+	"spa": "es", "spanish": "es",
+	"est": "et", "estonian": "et",
+	"eus": "eu", "baq": "eu", "basque": "eu",
+	"fas": "fa", "per": "fa", "persian": "fa",
+	"fin": "fi", "finnish": "fi",
+	"fra": "fr", "fre": "fr", "french": "fr",
+	"gle": "ga", "irish": "ga",
+	"glg": "gl", "galician": "gl",
+	"heb": "he", "hebrew": "he",
+	"hin": "hi", "hindi": "hi",
+	"hrv": "hr", "croatian": "hr",
+	"hun": "hu", "hungarian": "hu",
+	"hye": "hy", "arm": "hy", "armenian": "hy",
+	"ind": "id", "indonesian": "id",
+	"isl": "is", "ice": "is", "icelandic": "is",
+	"ita": "it", "italian": "it",
+	"jpn": "ja", "japanese": "ja",
+	"kat": "ka", "geo": "ka", "georgian": "ka",
+	"kaz": "kk", "kazakh": "kk",
+	"kor": "ko", "korean": "ko",
+	"kir": "ky", "kirghiz": "ky", "kyrgyz": "ky",
+	"lat": "la", "latin": "la",
+	"lit": "lt", "lithuanian": "lt",
+	"lav": "lv", "latvian": "lv",
+	"mkd": "mk", "mac": "mk", "macedonian": "mk",
+	"mon": "mn", "mongolian": "mn",
+	"nld": "nl", "dut": "nl", "dutch": "nl",
+	"nor": "no", "norwegian": "no",
+	"pol": "pl", "polish": "pl",
+	"por": "pt", "portuguese": "pt",
+	"ron": "ro", "rum": "ro", "romanian": "ro",
+	"rus": "ru", "russian": "ru",
+	"slk": "sk", "slo": "sk", "slovak": "sk",
+	"slv": "sl", "slovenian": "sl",
+	"sqi": "sq", "alb": "sq", "albanian": "sq",
+	"srp": "sr", "serbian": "sr",
+	"swe": "sv", "swedish": "sv",
+	"tgk": "tg", "tajik": "tg",
+	"tha": "th", "thai": "th",
+	"tuk": "tk", "turkmen": "tk",
+	"tur": "tr", "turkish": "tr",
+	"tat": "tt", "tatar": "tt",
+	"ukr": "uk", "ukrainian": "uk",
+	"uzb": "uz", "uzbek": "uz",
+	"vie": "vi", "vietnamese": "vi",
+	"yid": "yi", "yiddish": "yi",
+	"zho": "zh", "chi": "zh", "chinese": "zh",
+}
+
+// NormalizeLang converts a language code or an English language name to a lowercase ISO 639-1 code.
+// Unknown codes are returned in lower case.
+func NormalizeLang(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	// region subtags, like "en-US" or "pt_BR"
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	if c, ok := langCodes[code]; ok {
+		return c
+	}
+	return code
+}
+
+// LangNormalized returns a normalized language code of the book.
+// See NormalizeLang for details.
+func (b Book) LangNormalized() string {
+	return NormalizeLang(b.Lang)
+}
+
+// BooksByLang groups all books of the index by normalized language code.
+func (idx *Index) BooksByLang() map[string][]Book {
+	out := make(map[string][]Book)
+	for _, b := range idx.AllBooks() {
+		lang := b.LangNormalized()
+		out[lang] = append(out[lang], b)
+	}
+	return out
+}
+
+// GroupByLang splits the index into separate indexes for each normalized language code.
+// Books without language are put under the "unknown" key. Child indexes have the same
+// name, version and archive names as the original one.
+func (idx *Index) GroupByLang() map[string]*Index {
+	out := make(map[string]*Index)
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			lang := b.LangNormalized()
+			if lang == "" {
+				lang = "unknown"
+			}
+			sub := out[lang]
+			if sub == nil {
+				sub = &Index{
+					Name:        idx.Name,
+					Description: idx.Description,
+					Version:     idx.Version,
+					Structure:   idx.Structure,
+					Archives:    make(map[string][]Book),
+				}
+				out[lang] = sub
+			}
+			if _, ok := sub.Archives[name]; !ok {
+				sub.copyArchiveFile(idx, name)
+			}
+			sub.Archives[name] = append(sub.Archives[name], b)
+		}
+	}
+	return out
+}
@@ -0,0 +1,41 @@
+package inpx
+
+import "testing"
+
+func TestNormalizeLang(t *testing.T) {
+	for _, c := range []struct {
+		code, exp string
+	}{
+		{"ru", "ru"},
+		{"RU", "ru"},
+		{"rus", "ru"},
+		{" Russian ", "ru"},
+		{"en-US", "en"},
+		{"ger", "de"},
+		{"deu", "de"},
+		{"xx", "xx"},
+		{"", ""},
+	} {
+		if got := NormalizeLang(c.code); got != c.exp {
+			t.Errorf("NormalizeLang(%q) = %q, expected %q", c.code, got, c.exp)
+		}
+	}
+}
+
+func TestGroupByLang(t *testing.T) {
+	idx := &Index{Name: "lib", Version: "2", Archives: map[string][]Book{
+		"a": {{LibId: 1, Lang: "ru"}, {LibId: 2, Lang: "rus"}, {LibId: 3}},
+		"b": {{LibId: 4, Lang: "EN"}},
+	}}
+	groups := idx.GroupByLang()
+	if len(groups) != 3 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+	ru := groups["ru"]
+	if ru.Name != "lib" || ru.Version != "2" || len(ru.Archives) != 1 || len(ru.Archives["a"]) != 2 {
+		t.Fatalf("unexpected index: %+v", ru)
+	}
+	if len(groups["unknown"].Archives["a"]) != 1 || len(groups["en"].Archives["b"]) != 1 {
+		t.Fatalf("unexpected groups: %v", groups)
+	}
+}
@@ -0,0 +1,110 @@
+package inpx
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	atomNS = "http://www.w3.org/2005/Atom"
+	dcNS   = "http://purl.org/dc/terms/"
+
+	opdsAcquisition = "http://opds-spec.org/acquisition"
+)
+
+type opdsFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	NS      string      `xml:"xmlns,attr"`
+	DCNS    string      `xml:"xmlns:dc,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Entries []opdsEntry `xml:"entry"`
+}
+
+type opdsEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Authors    []opdsAuthor   `xml:"author"`
+	Categories []opdsCategory `xml:"category"`
+	Language   string         `xml:"dc:language,omitempty"`
+	Links      []opdsLink     `xml:"link"`
+}
+
+type opdsAuthor struct {
+	Name string `xml:"name"`
+}
+
+type opdsCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type opdsLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// WriteOPDS writes an OPDS acquisition feed (Atom) with all books of the index that are not
+// marked as deleted. Download links are constructed as baseURL/archive/name.ext, with a type
+// from Book.ContentType. Archive and file names are escaped, as in Book.DownloadURL.
+func WriteOPDS(w io.Writer, idx *Index, baseURL string) error {
+	feed := opdsFeed{
+		NS:    atomNS,
+		DCNS:  dcNS,
+		ID:    "urn:inpx:" + idx.Name,
+		Title: idx.Name,
+	}
+	var updated time.Time
+	for _, b := range idx.AllBooks() {
+		if b.Deleted {
+			continue
+		}
+		if b.Date.After(updated) {
+			updated = b.Date
+		}
+		e := opdsEntry{
+			ID:       "urn:inpx:" + idx.Name + ":" + strconv.Itoa(b.LibId),
+			Title:    b.Title,
+			Updated:  atomTime(b.Date),
+			Language: b.Lang,
+		}
+		for _, a := range b.Authors {
+			e.Authors = append(e.Authors, opdsAuthor{Name: a.FullName()})
+		}
+		for _, g := range b.Genres {
+			if g == "" {
+				continue
+			}
+			e.Categories = append(e.Categories, opdsCategory{Term: g, Label: GenreName(g)})
+		}
+		if b.File.Name != "" {
+			e.Links = append(e.Links, opdsLink{
+				Rel:  opdsAcquisition,
+				Href: baseURL + "/" + url.PathEscape(b.File.Archive) + "/" + url.PathEscape(b.File.zipName()),
+				Type: b.ContentType(),
+			})
+		}
+		feed.Entries = append(feed.Entries, e)
+	}
+	feed.Updated = atomTime(updated)
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,60 @@
+package inpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+	"time"
+)
+
+func TestWriteOPDS(t *testing.T) {
+	idx := &Index{Name: "lib", Archives: map[string][]Book{
+		"fb2-001": {
+			{
+				LibId:   1,
+				Title:   "Title & Co",
+				Authors: []Author{newAuthor([]string{"Doe", "John"})},
+				Genres:  []string{"sf", ""},
+				Lang:    "en",
+				Date:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				File:    File{Name: "1", Ext: "fb2", Archive: "fb2-001"},
+			},
+			{LibId: 2, Title: "Deleted", Deleted: true},
+		},
+		"fb2-002": {
+			{LibId: 3, Title: "Escaped", File: File{Name: "книга #1?", Ext: "fb2", Archive: "my books"}},
+		},
+	}}
+	var buf bytes.Buffer
+	if err := WriteOPDS(&buf, idx, "http://example.com/books"); err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title    string `xml:"title"`
+			Author   string `xml:"author>name"`
+			Language string `xml:"http://purl.org/dc/terms/ language"`
+			Category struct {
+				Term string `xml:"term,attr"`
+			} `xml:"category"`
+			Link struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &feed); err != nil {
+		t.Fatal(err)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("unexpected entries:\n%s", buf.String())
+	}
+	e := feed.Entries[0]
+	if e.Title != "Title & Co" || e.Author != "John Doe" || e.Language != "en" || e.Category.Term != "sf" ||
+		e.Link.Href != "http://example.com/books/fb2-001/1.fb2" || feed.Updated != "2020-01-02T00:00:00Z" {
+		t.Fatalf("unexpected feed:\n%s", buf.String())
+	}
+	if href, exp := feed.Entries[1].Link.Href, "http://example.com/books/my%20books/%D0%BA%D0%BD%D0%B8%D0%B3%D0%B0%20%231%3F.fb2"; href != exp {
+		t.Fatalf("unexpected link: %q", href)
+	}
+}
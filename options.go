@@ -0,0 +1,298 @@
+package inpx
+
+import (
+	"errors"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// Default separators used in inp files.
+const (
+	DefaultFieldSeparator = 0x04
+	DefaultLineSeparator  = '\n'
+)
+
+// Option is an option for Open.
+type Option func(*config)
+
+// config holds settings for reading inpx files.
+type config struct {
+	structure   []int
+	onError     func(error)
+	progress    func(done, total int)
+	concurrency int
+	encoding    encoding.Encoding
+	include     []string
+	offsets     bool
+	fieldSep    byte
+	lineSep     byte
+	exclude     []string
+	preserveRaw bool
+	onTruncated TruncatedLineHandler
+	loadFields  []int
+	dateLoc     *time.Location
+	lazy        bool
+	strict      bool
+	// logger receives warnings as a message and key-value pairs, as in slog.Logger.Warn.
+	// If it is not set, warnings are written with log.Println.
+	logger func(msg string, args ...interface{})
+
+	mu          sync.Mutex // serializes onError calls
+	collectErrs bool       // collect parse errors for Index.ParseErrors
+	parseErrs   []*ParseError
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		structure:   DefaultStructure,
+		concurrency: 1,
+		fieldSep:    DefaultFieldSeparator,
+		lineSep:     DefaultLineSeparator,
+		dateLoc:     time.UTC,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.concurrency < 1 {
+		c.concurrency = 1
+	}
+	return c
+}
+
+// handleError reports a non-fatal error of a single record.
+func (c *config) handleError(err error) {
+	var perr *ParseError
+	if c.collectErrs && errors.As(err, &perr) {
+		c.mu.Lock()
+		c.parseErrs = append(c.parseErrs, perr)
+		c.mu.Unlock()
+	}
+	if c.onTruncated != nil && errors.As(err, &perr) && errors.Is(perr.Cause, ErrTruncated) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.onTruncated(perr.Archive, perr.Line, perr.Raw)
+		return
+	}
+	if c.onError == nil {
+		if c.logger != nil {
+			c.logger("malformed inp record", "error", err)
+		} else {
+			log.Println("err:", err)
+		}
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError(err)
+}
+
+// takeParseErrors returns parse errors collected since the last call, sorted by archive and line.
+func (c *config) takeParseErrors() []*ParseError {
+	c.mu.Lock()
+	errs := c.parseErrs
+	c.parseErrs = nil
+	c.mu.Unlock()
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Archive != errs[j].Archive {
+			return errs[i].Archive < errs[j].Archive
+		}
+		return errs[i].Line < errs[j].Line
+	})
+	return errs
+}
+
+// loadStructure returns a field structure that is used for parsing inp files. Fields that
+// should not be loaded (see WithLoadFields) are replaced with fieldSkipped.
+func (c *config) loadStructure(structure []int) []int {
+	if c.loadFields == nil {
+		return structure
+	}
+	load := make(map[int]bool, len(c.loadFields))
+	for _, f := range c.loadFields {
+		load[f] = true
+	}
+	out := make([]int, len(structure))
+	for i, f := range structure {
+		if !load[f] {
+			f = fieldSkipped
+		}
+		out[i] = f
+	}
+	return out
+}
+
+// trimLine removes the line separator from the end of the line.
+// If lines are separated with '\n', a preceding '\r' is removed as well.
+func (c *config) trimLine(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == c.lineSep {
+		line = line[:n-1]
+		if n := len(line); c.lineSep == '\n' && n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+	}
+	return line
+}
+
+// matchAny checks if the name matches any of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// includeArchive checks if archive with a given name should be read.
+func (c *config) includeArchive(name string) bool {
+	if len(c.include) != 0 && !matchAny(c.include, name) {
+		return false
+	}
+	return !matchAny(c.exclude, name)
+}
+
+// WithStructure sets a field structure for inp files.
+// It is ignored if inpx contains a structure.info file.
+func WithStructure(structure []int) Option {
+	return func(c *config) {
+		c.structure = structure
+	}
+}
+
+// WithErrorHandler sets a function that is called for each record that cannot be parsed.
+// Such records are skipped. Errors passed to the function are of *ParseError type.
+// By default, errors are written to the standard logger.
+func WithErrorHandler(fn func(error)) Option {
+	return func(c *config) {
+		c.onError = fn
+	}
+}
+
+// TruncatedLineHandler is called for each inp line that has fewer fields than expected.
+// Line numbers start from 1, raw is a line without the line separator.
+type TruncatedLineHandler func(archive string, lineNum int, raw []byte)
+
+// WithTruncatedLineHandler sets a function that is called for each truncated inp line
+// instead of the error handler. Such lines are skipped. By default, truncated lines
+// are reported to the error handler (see WithErrorHandler) as *ParseError wrapping ErrTruncated.
+func WithTruncatedLineHandler(fn TruncatedLineHandler) Option {
+	return func(c *config) {
+		c.onTruncated = fn
+	}
+}
+
+// WithProgress sets a function that is called each time an inp file is read.
+func WithProgress(fn func(done, total int)) Option {
+	return func(c *config) {
+		c.progress = fn
+	}
+}
+
+// WithConcurrency sets the number of inp files that are read in parallel.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithEncoding sets an encoding of inp files. By default, files are expected to be in UTF-8,
+// and lines that are not valid UTF-8 are decoded from Windows-1251. Use encoding.Nop
+// to keep such lines as is.
+func WithEncoding(enc encoding.Encoding) Option {
+	return func(c *config) {
+		c.encoding = enc
+	}
+}
+
+// WithIncludeArchives sets glob patterns (see filepath.Match) for archive names that should be read.
+// Other archives are skipped. By default, all archives are read.
+func WithIncludeArchives(patterns ...string) Option {
+	return func(c *config) {
+		c.include = append(c.include, patterns...)
+	}
+}
+
+// WithExcludeArchives sets glob patterns (see filepath.Match) for archive names that should be skipped,
+// even if they match patterns set by WithIncludeArchives.
+func WithExcludeArchives(patterns ...string) Option {
+	return func(c *config) {
+		c.exclude = append(c.exclude, patterns...)
+	}
+}
+
+// WithFileOffsets enables reading the directory of each book archive to populate data offsets
+// of book files. This makes File.OpenFast faster, but requires opening all book archives.
+func WithFileOffsets() Option {
+	return func(c *config) {
+		c.offsets = true
+	}
+}
+
+// WithStrict makes reading fail on the first malformed record, instead of skipping it.
+// The returned error is a ParseError. Error handlers are not called in strict mode.
+func WithStrict() Option {
+	return func(c *config) {
+		c.strict = true
+	}
+}
+
+// WithLazyLoad defers parsing of inp files until the archive is accessed with Index.Archive.
+// Archives of the opened index is empty, names of archives are returned by Index.UnloadedArchives.
+// The inpx file is kept open until Index.Close is called.
+//
+// Methods that write or export the whole index (Save, Snapshot, Export, ImportBookFile) load all
+// archives first. Other methods of Index only see archives that are already loaded; call
+// Index.LoadAll before using them on the whole library.
+func WithLazyLoad() Option {
+	return func(c *config) {
+		c.lazy = true
+	}
+}
+
+// WithPreserveRaw enables storing raw bytes of each inp line in Book.Raw.
+// It is useful for debugging, but doubles the memory used by the index.
+func WithPreserveRaw() Option {
+	return func(c *config) {
+		c.preserveRaw = true
+	}
+}
+
+// WithLoadFields sets fields that are populated when reading books (e.g. FieldTitle, FieldLibId).
+// Other fields are left empty, and LibRate is set to -1, which reduces memory used by the index.
+// File.Dir and File.Archive are always populated. By default, all fields are loaded.
+func WithLoadFields(fields ...int) Option {
+	return func(c *config) {
+		c.loadFields = append([]int{}, fields...)
+	}
+}
+
+// WithDateLocation sets a time zone that is used to interpret dates of books.
+// Default is UTC, a nil location is treated as UTC as well.
+func WithDateLocation(loc *time.Location) Option {
+	return func(c *config) {
+		if loc == nil {
+			loc = time.UTC
+		}
+		c.dateLoc = loc
+	}
+}
+
+// WithFieldSeparator sets a separator of fields in inp files. Default is 0x04.
+func WithFieldSeparator(sep byte) Option {
+	return func(c *config) {
+		c.fieldSep = sep
+	}
+}
+
+// WithLineSeparator sets a separator of lines in inp files. Default is '\n'.
+// Lines ending with "\r\n" are accepted with the default separator.
+func WithLineSeparator(sep byte) Option {
+	return func(c *config) {
+		c.lineSep = sep
+	}
+}
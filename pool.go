@@ -0,0 +1,204 @@
+package inpx
+
+import (
+	"archive/zip"
+	"container/list"
+	"errors"
+	"io"
+	"sync"
+)
+
+// ArchivePool keeps a limited number of book archives open, so files from the same
+// archive can be opened without reading the zip directory each time.
+// It is safe for concurrent use.
+type ArchivePool struct {
+	maxOpen int
+
+	mu      sync.Mutex
+	closed  bool
+	lru     *list.List // of *poolEntry; most recently used first
+	entries map[string]*list.Element
+}
+
+type poolEntry struct {
+	path    string
+	ready   chan struct{} // closed when the archive is opened; zr and err are set after that
+	zr      *zip.ReadCloser
+	err     error
+	refs    int
+	evicted bool // closed when the last reference is released
+}
+
+// close closes the archive of the entry, if it was opened.
+func (e *poolEntry) close() error {
+	if e.zr == nil {
+		return nil
+	}
+	return e.zr.Close()
+}
+
+// NewArchivePool creates a pool that keeps at most maxOpen archives open.
+// Archives that are in use are closed when the last file opened from them is closed.
+func NewArchivePool(maxOpen int) *ArchivePool {
+	if maxOpen < 1 {
+		maxOpen = 1
+	}
+	return &ArchivePool{
+		maxOpen: maxOpen,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+var errPoolClosed = errors.New("archive pool is closed")
+
+// acquire returns an entry for the archive, opening it if necessary. The archive is opened
+// without holding mu, so callers that use other archives are not blocked by a slow open.
+// Concurrent callers for the same archive wait for the first one to open it.
+func (p *ArchivePool) acquire(path string) (*poolEntry, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	if el, ok := p.entries[path]; ok {
+		p.lru.MoveToFront(el)
+		e := el.Value.(*poolEntry)
+		e.refs++
+		p.mu.Unlock()
+		<-e.ready
+		if e.err != nil {
+			p.release(e)
+			return nil, e.err
+		}
+		return e, nil
+	}
+	e := &poolEntry{path: path, ready: make(chan struct{}), refs: 1}
+	p.entries[path] = p.lru.PushFront(e)
+	for p.lru.Len() > p.maxOpen {
+		p.evict(p.lru.Back())
+	}
+	p.mu.Unlock()
+
+	e.zr, e.err = zip.OpenReader(path)
+	close(e.ready)
+	if e.err != nil {
+		p.mu.Lock()
+		if el, ok := p.entries[path]; ok && el.Value == e {
+			p.lru.Remove(el)
+			delete(p.entries, path)
+		}
+		e.refs--
+		p.mu.Unlock()
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// evict removes an entry from the pool. It must be called with mu held.
+func (p *ArchivePool) evict(el *list.Element) error {
+	e := p.lru.Remove(el).(*poolEntry)
+	delete(p.entries, e.path)
+	if e.refs == 0 {
+		return e.close()
+	}
+	e.evicted = true
+	return nil
+}
+
+func (p *ArchivePool) release(e *poolEntry) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e.refs--
+	if e.refs == 0 && e.evicted {
+		return e.close()
+	}
+	return nil
+}
+
+// Close closes all archives in the pool and returns the first error. Archives that are still
+// in use are closed when all files opened from them are closed.
+func (p *ArchivePool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	var first error
+	for p.lru.Len() != 0 {
+		if err := p.evict(p.lru.Front()); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+type pooledFile struct {
+	io.ReadCloser
+	pool  *ArchivePool
+	entry *poolEntry
+	once  sync.Once
+}
+
+func (f *pooledFile) Close() error {
+	err := f.ReadCloser.Close()
+	f.once.Do(func() {
+		if err2 := f.pool.release(f.entry); err == nil {
+			err = err2
+		}
+	})
+	return err
+}
+
+// OpenVia is like Open, but reuses archives opened by the pool. If the file is split
+// across several archives, all parts are opened through the pool and read one after another.
+func (fr File) OpenVia(pool *ArchivePool) (io.ReadCloser, error) {
+	return fr.openParts(func(part File) (io.ReadCloser, error) {
+		return part.openPartVia(pool)
+	})
+}
+
+// openPartVia opens a book file from a single archive of the pool, ignoring AdditionalArchives.
+func (fr File) openPartVia(pool *ArchivePool) (io.ReadCloser, error) {
+	e, err := pool.acquire(fr.AbsolutePath())
+	if err == errPoolClosed {
+		return nil, err
+	} else if err != nil {
+		return nil, &ArchiveOpenError{Archive: fr.Archive, Cause: err}
+	}
+	f := fr.find(&e.zr.Reader)
+	if f == nil {
+		pool.release(e)
+		return nil, &MissingFileError{File: fr}
+	}
+	rc, err := f.Open()
+	if err != nil {
+		pool.release(e)
+		return nil, err
+	}
+	return &pooledFile{ReadCloser: rc, pool: pool, entry: e}, nil
+}
+
+// OpenFiles opens files of all books in parallel, with at most concurrency files being opened at once.
+// Results are returned in the same order as books. If a file cannot be opened, the corresponding
+// reader is nil and the error is set. Callers must close all returned readers.
+func OpenFiles(books []Book, concurrency int) ([]io.ReadCloser, []error) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	files := make([]io.ReadCloser, len(books))
+	errs := make([]error, len(books))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range books {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			files[i], errs[i] = books[i].File.Open()
+		}(i)
+	}
+	wg.Wait()
+	return files, errs
+}
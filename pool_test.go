@@ -0,0 +1,113 @@
+package inpx
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestArchivePool(t *testing.T) {
+	dir := t.TempDir()
+	var files []File
+	for i := 0; i < 3; i++ {
+		arch := "fb2-" + strconv.Itoa(i)
+		writeTestZip(t, filepath.Join(dir, arch+".zip"), map[string]string{
+			"1.fb2": arch + "/1",
+			"2.fb2": arch + "/2",
+		})
+		for _, name := range []string{"1", "2"} {
+			files = append(files, File{Dir: dir, Archive: arch, Name: name, Ext: "fb2"})
+		}
+	}
+	pool := NewArchivePool(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, fr := range files {
+				rc, err := fr.OpenVia(pool)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				data, err := ioutil.ReadAll(rc)
+				rc.Close()
+				if err != nil {
+					t.Error(err)
+				} else if exp := fr.Archive + "/" + fr.Name; string(data) != exp {
+					t.Errorf("unexpected content: %q vs %q", data, exp)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if n := pool.lru.Len(); n > 2 {
+		t.Fatalf("too many open archives: %d", n)
+	}
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[0].OpenVia(pool); err == nil {
+		t.Fatal("expected an error for closed pool")
+	}
+}
+
+func TestArchivePoolErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "a.zip"), map[string]string{"1.fb2": "a/1"})
+	pool := NewArchivePool(2)
+	if _, err := (File{Dir: dir, Archive: "b", Name: "1", Ext: "fb2"}).OpenVia(pool); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := pool.lru.Len(); n != 0 {
+		t.Fatalf("failed archive is kept in the pool: %d", n)
+	}
+	rc, err := (File{Dir: dir, Archive: "a", Name: "1", Ext: "fb2"}).OpenVia(pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// close the archive behind the pool's back, so closing it again fails
+	e := pool.lru.Front().Value.(*poolEntry)
+	if err = e.zr.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = pool.Close(); err == nil {
+		t.Fatal("expected an error from Close")
+	}
+}
+
+func TestOpenFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "fb2-001.zip"), map[string]string{
+		"1.fb2": "book 1",
+		"2.fb2": "book 2",
+	})
+	var books []Book
+	for _, name := range []string{"1", "missing", "2"} {
+		books = append(books, Book{File: File{Dir: dir, Archive: "fb2-001", Name: name, Ext: "fb2"}})
+	}
+	files, errs := OpenFiles(books, 2)
+	if errs[0] != nil || errs[1] == nil || errs[2] != nil || files[1] != nil {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	for i, exp := range []string{"book 1", "", "book 2"} {
+		if files[i] == nil {
+			continue
+		}
+		data, err := ioutil.ReadAll(files[i])
+		files[i].Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if string(data) != exp {
+			t.Fatalf("unexpected content: %q", data)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package inpx
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+// ResumeToken is a position of IndexReader in the inpx file. It can be serialized
+// and passed to IndexReader.Resume to continue reading from the same record.
+type ResumeToken struct {
+	Member int   // index of the inp file in the inpx zip
+	Offset int64 // byte offset in the inp file
+	Line   int   // number of lines read from the inp file
+}
+
+// IndexReader reads books from an inpx file one by one, without loading the whole index.
+type IndexReader struct {
+	zf    *zip.ReadCloser
+	c     *config
+	dir   string
+	index *Index
+
+	member int // index of the current member in zf.File
+	rc     io.ReadCloser
+	inp    *inpReader
+}
+
+// NewIndexReader opens an inpx file for incremental reading.
+func NewIndexReader(path string, opts ...Option) (*IndexReader, error) {
+	c := newConfig(opts)
+	zf, err := zip.OpenReader(path)
+	if err == zip.ErrFormat {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	} else if err != nil {
+		return nil, err
+	}
+	index, err := readHeader(&zf.Reader, c)
+	if err != nil {
+		zf.Close()
+		return nil, err
+	}
+	return &IndexReader{zf: zf, c: c, dir: filepath.Dir(path), index: index, member: -1}, nil
+}
+
+// Header returns library metadata. Archives of the returned index are always empty.
+func (r *IndexReader) Header() *Index {
+	return r.index
+}
+
+// openMember starts reading inp file with a given index, skipping off bytes.
+func (r *IndexReader) openMember(i int, off int64, line int) error {
+	r.closeMember()
+	r.member = i
+	if i >= len(r.zf.File) {
+		return nil
+	}
+	f := r.zf.File[i]
+	pack, _ := inpArchive(f, r.c)
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("error while reading inp: %v", err)
+	}
+	if off > 0 {
+		if _, err = io.CopyN(io.Discard, rc, off); err != nil {
+			rc.Close()
+			return fmt.Errorf("error while seeking inp: %v", err)
+		}
+	}
+	r.rc = rc
+	r.inp = newInpReader(rc, pack, r.dir, r.c.loadStructure(r.index.Structure), r.c)
+	r.inp.off = off
+	r.inp.line = line
+	return nil
+}
+
+func (r *IndexReader) closeMember() {
+	if r.rc != nil {
+		r.rc.Close()
+		r.rc, r.inp = nil, nil
+	}
+}
+
+// nextMember returns the index of the next inp file to read, starting from i.
+func (r *IndexReader) nextMember(i int) int {
+	for ; i < len(r.zf.File); i++ {
+		if _, ok := inpArchive(r.zf.File[i], r.c); ok {
+			break
+		}
+	}
+	return i
+}
+
+// Next returns an archive name and the next book from the index.
+// It returns io.EOF when there are no more books.
+func (r *IndexReader) Next() (string, Book, error) {
+	for {
+		if r.inp == nil {
+			i := r.nextMember(r.member + 1)
+			if i >= len(r.zf.File) {
+				r.member = i
+				return "", Book{}, io.EOF
+			}
+			if err := r.openMember(i, 0, 0); err != nil {
+				return "", Book{}, err
+			}
+		}
+		b, err := r.inp.next()
+		if err == io.EOF {
+			r.closeMember()
+			continue
+		} else if err != nil {
+			return "", Book{}, err
+		}
+		return r.inp.pack, b, nil
+	}
+}
+
+// Position returns a token that points to the next book returned by Next.
+func (r *IndexReader) Position() ResumeToken {
+	if r.inp == nil {
+		return ResumeToken{Member: r.nextMember(r.member + 1)}
+	}
+	return ResumeToken{Member: r.member, Offset: r.inp.off, Line: r.inp.line}
+}
+
+// Resume moves the reader to a position returned by Position.
+func (r *IndexReader) Resume(tok ResumeToken) error {
+	if tok.Member < 0 || tok.Member > len(r.zf.File) {
+		return fmt.Errorf("invalid resume token: member %d", tok.Member)
+	}
+	if tok.Member < len(r.zf.File) {
+		if _, ok := inpArchive(r.zf.File[tok.Member], r.c); !ok {
+			return fmt.Errorf("invalid resume token: %q is not an inp file", r.zf.File[tok.Member].Name)
+		}
+	}
+	return r.openMember(tok.Member, tok.Offset, tok.Line)
+}
+
+// Close releases the inpx file.
+func (r *IndexReader) Close() error {
+	r.closeMember()
+	return r.zf.Close()
+}
+
+// Walk reads an inpx file record by record and calls fn for each book, without loading
+// the whole index. It stops on the first error returned by fn.
+func Walk(path string, fn func(archive string, b Book) error, opts ...Option) error {
+	r, err := NewIndexReader(path, opts...)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for {
+		pack, b, err := r.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err = fn(pack, b); err != nil {
+			return err
+		}
+	}
+}
+
+// WalkDeduped is like Walk, but only calls fn for the first book with each key.
+// It is a streaming equivalent of Index.FindDuplicatesBy. Keys are kept in memory.
+func WalkDeduped(path string, key func(Book) string, fn func(archive string, b Book) error, opts ...Option) error {
+	seen := make(map[string]struct{})
+	return Walk(path, func(archive string, b Book) error {
+		k := key(b)
+		if _, ok := seen[k]; ok {
+			return nil
+		}
+		seen[k] = struct{}{}
+		return fn(archive, b)
+	}, opts...)
+}
+
+// Decoder streams books from an inpx file one at a time. It is a simpler alternative to IndexReader
+// that exposes boundaries of archives with Archive and ArchiveStart.
+type Decoder struct {
+	r       *IndexReader
+	member  int // member of the last book, see IndexReader.member
+	archive string
+	start   bool
+}
+
+// NewDecoder opens an inpx file for streaming.
+func NewDecoder(path string, opts ...Option) (*Decoder, error) {
+	r, err := NewIndexReader(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Decoder{r: r, member: -1}, nil
+}
+
+// Header returns library metadata. See IndexReader.Header.
+func (d *Decoder) Header() *Index {
+	return d.r.Header()
+}
+
+// Next returns the next book from the index. It returns io.EOF when there are no more books.
+func (d *Decoder) Next() (Book, error) {
+	pack, b, err := d.r.Next()
+	if err != nil {
+		return Book{}, err
+	}
+	d.start = d.r.member != d.member
+	d.member, d.archive = d.r.member, pack
+	return b, nil
+}
+
+// Archive returns the name of the archive (inp file without extension) of the last book returned by Next.
+func (d *Decoder) Archive() string {
+	return d.archive
+}
+
+// ArchiveStart checks if the last book returned by Next is the first book of its archive.
+func (d *Decoder) ArchiveStart() bool {
+	return d.start
+}
+
+// SkipArchive skips remaining books of the current archive. The next call to Next returns
+// the first book of the next archive.
+func (d *Decoder) SkipArchive() {
+	d.r.closeMember()
+}
+
+// Close releases the inpx file.
+func (d *Decoder) Close() error {
+	return d.r.Close()
+}
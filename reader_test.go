@@ -0,0 +1,132 @@
+package inpx
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func readAllRecords(t testing.TB, r *IndexReader) []string {
+	var out []string
+	for {
+		pack, b, err := r.Next()
+		if err == io.EOF {
+			return out
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, pack+"/"+b.Title)
+	}
+}
+
+func TestIndexReaderResume(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp":           "A1\nA2\nA3\n",
+		"b.inp":           "B1\nB2",
+		"collection.info": "Test\n",
+		"structure.info":  "TITLE",
+	})
+	r, err := NewIndexReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if name := r.Header().Name; name != "Test" {
+		t.Fatalf("unexpected name: %q", name)
+	}
+	all := readAllRecords(t, r)
+	exp := []string{"a/A1", "a/A2", "a/A3", "b/B1", "b/B2"}
+	if !reflect.DeepEqual(all, exp) {
+		t.Fatalf("unexpected records: %q", all)
+	}
+	for n := 0; n <= len(exp); n++ {
+		r, err := NewIndexReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			if _, _, err := r.Next(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		tok := r.Position()
+		r.Close()
+
+		r, err = NewIndexReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = r.Resume(tok); err != nil {
+			t.Fatal(err)
+		}
+		got := readAllRecords(t, r)
+		r.Close()
+		if !reflect.DeepEqual(got, append([]string(nil), exp[n:]...)) {
+			t.Fatalf("resume after %d records (%+v): got %q", n, tok, got)
+		}
+	}
+}
+
+func TestWalkDeduped(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp":          "A\x041\nB\x042\n",
+		"b.inp":          "A\x043\nC\x044\n",
+		"structure.info": "TITLE;LIBID",
+	})
+	var got []string
+	err := WalkDeduped(path, func(b Book) string { return b.Title }, func(archive string, b Book) error {
+		got = append(got, archive+"/"+b.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := []string{"a/A", "a/B", "b/C"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected books: %q", got)
+	}
+	errStop := errors.New("stop")
+	n := 0
+	err = Walk(path, func(archive string, b Book) error {
+		n++
+		return errStop
+	})
+	if err != errStop || n != 1 {
+		t.Fatalf("unexpected result: %v, %d", err, n)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp":          "A1\nA2\nA3\n",
+		"b.inp":          "B1\nB2",
+		"c.inp":          "C1\n",
+		"structure.info": "TITLE",
+	})
+	d, err := NewDecoder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	var got []string
+	for {
+		b, err := d.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		s := d.Archive() + "/" + b.Title
+		if d.ArchiveStart() {
+			s = "+" + s
+		}
+		got = append(got, s)
+		if b.Title == "B1" {
+			d.SkipArchive()
+		}
+	}
+	exp := []string{"+a/A1", "a/A2", "a/A3", "+b/B1", "+c/C1"}
+	if !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected records: %q", got)
+	}
+}
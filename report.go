@@ -0,0 +1,80 @@
+package inpx
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+)
+
+// reportTop is the number of authors and genres listed in the report.
+const reportTop = 10
+
+type keyCount struct {
+	Key   string
+	Count int
+}
+
+// topCounts returns at most n keys with the highest counts. Keys with the same count are sorted by name.
+func topCounts(m map[string]int, n int) []keyCount {
+	out := make([]keyCount, 0, len(m))
+	for k, v := range m {
+		out = append(out, keyCount{Key: k, Count: v})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// PrintReport writes a human-readable summary of the index: overall stats, top authors and genres,
+// and the number of records in each archive. Deleted books are not counted in stats
+// and top lists, but are included in archive record counts.
+func (idx *Index) PrintReport(w io.Writer) error {
+	langs := make(map[string]struct{})
+	total := 0
+	for _, books := range idx.Archives {
+		for _, b := range books {
+			if b.Deleted {
+				continue
+			}
+			total++
+			if lang := b.LangNormalized(); lang != "" {
+				langs[lang] = struct{}{}
+			}
+		}
+	}
+	genres := idx.GenreDistribution()
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if idx.Name != "" {
+		fmt.Fprintf(tw, "Collection:\t%s\n", idx.Name)
+	}
+	if idx.Version != "" {
+		fmt.Fprintf(tw, "Version:\t%s\n", idx.Version)
+	}
+	fmt.Fprintf(tw, "Books:\t%d\n", total)
+	fmt.Fprintf(tw, "Archives:\t%d\n", len(idx.Archives))
+	fmt.Fprintf(tw, "Languages:\t%d\n", len(langs))
+	fmt.Fprintf(tw, "Genres:\t%d\n", len(genres))
+
+	fmt.Fprintf(tw, "\nTop authors:\n")
+	for _, a := range idx.MostProlificAuthors(reportTop) {
+		fmt.Fprintf(tw, "  %s\t%d\n", a.Author.FullName(), a.Count)
+	}
+	fmt.Fprintf(tw, "\nTop genres:\n")
+	for _, g := range topCounts(genres, reportTop) {
+		fmt.Fprintf(tw, "  %s\t%d\n", GenreName(g.Key), g.Count)
+	}
+	fmt.Fprintf(tw, "\nArchives:\n")
+	for _, name := range idx.SortedArchiveNames() {
+		fmt.Fprintf(tw, "  %s\t%d\n", name, len(idx.Archives[name]))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,45 @@
+package inpx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrintReport(t *testing.T) {
+	doe := newAuthor([]string{"Doe", "John"})
+	roe := newAuthor([]string{"Roe", "Jane"})
+	idx := &Index{Name: "Test", Archives: map[string][]Book{
+		"fb2-10": {
+			{Authors: []Author{doe}, Genres: []string{"sf"}, Lang: "ru"},
+			{Authors: []Author{doe, roe}, Genres: []string{"sf", "unknown_genre"}, Lang: "en"},
+		},
+		"fb2-9": {
+			{Authors: []Author{roe}, Deleted: true},
+		},
+	}}
+	var buf bytes.Buffer
+	if err := idx.PrintReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	exp := `Collection:  Test
+Books:       2
+Archives:    2
+Languages:   2
+Genres:      2
+
+Top authors:
+  John Doe  2
+  Jane Roe  1
+
+Top genres:
+  Science fiction  2
+  unknown_genre    1
+
+Archives:
+  fb2-9   1
+  fb2-10  2
+`
+	if buf.String() != exp {
+		t.Fatalf("unexpected report:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,44 @@
+package inpx
+
+import "regexp"
+
+// matchField checks if a given field of the book matches the regexp.
+// Only FieldTitle, FieldAuthor, FieldSeries and FieldKeywords are supported, other fields never match.
+func (b Book) matchField(query *regexp.Regexp, field int) bool {
+	switch field {
+	case FieldTitle:
+		return query.MatchString(b.Title)
+	case FieldSeries:
+		return query.MatchString(b.Series)
+	case FieldAuthor:
+		for _, a := range b.Authors {
+			if query.MatchString(a.FullName()) {
+				return true
+			}
+		}
+	case FieldKeywords:
+		for _, k := range b.Keywords {
+			if query.MatchString(k) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Search returns all books that have at least one of the specified fields matching the regexp.
+// Supported fields are FieldTitle, FieldAuthor, FieldSeries and FieldKeywords. Authors are matched
+// by full name, keywords are matched one by one.
+// Books are returned in the same order as AllBooks.
+func (idx *Index) Search(query *regexp.Regexp, fields []int) []Book {
+	var out []Book
+	for _, b := range idx.AllBooks() {
+		for _, f := range fields {
+			if b.matchField(query, f) {
+				out = append(out, b)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,121 @@
+package inpx
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"b": {
+			{LibId: 3, Title: "Other", Series: "The Foundation"},
+			{LibId: 4, Title: "Empire", Keywords: []string{"galaxy", "foundation"}},
+		},
+		"a": {
+			{LibId: 1, Title: "Foundation"},
+			{LibId: 2, Title: "Robots", Authors: []Author{newAuthor([]string{"Foundation", "Isaac"})}},
+		},
+	}}
+	re := regexp.MustCompile(`(?i)foundation`)
+	for _, c := range []struct {
+		fields []int
+		ids    []int
+	}{
+		{[]int{FieldTitle}, []int{1}},
+		{[]int{FieldAuthor}, []int{2}},
+		{[]int{FieldTitle, FieldAuthor, FieldSeries}, []int{1, 2, 3}},
+		{[]int{FieldKeywords}, []int{4}},
+		{[]int{FieldLang}, nil},
+	} {
+		var ids []int
+		for _, b := range idx.Search(re, c.fields) {
+			ids = append(ids, b.LibId)
+		}
+		if len(ids) != len(c.ids) {
+			t.Fatalf("%v: unexpected results: %v", c.fields, ids)
+		}
+		for i := range ids {
+			if ids[i] != c.ids[i] {
+				t.Fatalf("%v: unexpected results: %v", c.fields, ids)
+			}
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Title: "Foundation"},
+			{LibId: 2, Title: "Foundation and Empire"},
+			{LibId: 3, Title: "Robots"},
+			{LibId: 4, Title: "foundation"},
+		},
+	}}
+	res := idx.FuzzySearch("Foundaton", 0.5)
+	var ids []int
+	for _, r := range res {
+		ids = append(ids, r.Book.LibId)
+		if r.Score <= 0 || r.Score > 1 {
+			t.Fatalf("unexpected score: %v", r.Score)
+		}
+	}
+	if !reflect.DeepEqual(ids, []int{1, 4}) {
+		t.Fatalf("unexpected results: %v", res)
+	}
+	ti := idx.BuildTitleIndex()
+	if res := ti.Search("foundation", 1); len(res) != 2 || res[0].Score != 1 {
+		t.Fatalf("unexpected results: %v", res)
+	}
+	if res := ti.Search("robots", 0.1); len(res) != 1 || res[0].Book.LibId != 3 {
+		t.Fatalf("unexpected results: %v", res)
+	}
+}
+
+func TestAuthorScore(t *testing.T) {
+	a := Author{LastName: "Tolstoy", FirstName: "Lev", MiddleName: "Nikolayevich"}
+	for _, c := range []struct {
+		query string
+		exp   float64
+	}{
+		{"tolstoy", 1},
+		{" LEV ", 1},
+		{"Tol", 0.8},
+		{"nikol", 0.8},
+		{"lsto", 0.5},
+		{"Tolstoj", 0.3},
+		{"Dostoevsky", 0},
+		{"", 0},
+	} {
+		if got := a.Score(c.query); got != c.exp {
+			t.Errorf("Score(%q) = %v, expected %v", c.query, got, c.exp)
+		}
+	}
+}
+
+func TestSearchByAuthor(t *testing.T) {
+	tolstoy := Author{LastName: "Tolstoy", FirstName: "Lev"}
+	tolstoy2 := Author{LastName: "TOLSTOY", FirstName: "lev"}
+	atolstoy := Author{LastName: "Tolstoy", FirstName: "Aleksey"}
+	tolkien := Author{LastName: "Tolkien", FirstName: "John"}
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Authors: []Author{tolstoy}},
+			{LibId: 2, Authors: []Author{tolkien}},
+			{LibId: 3, Authors: []Author{tolstoy2, atolstoy}},
+		},
+	}}
+	res := idx.SearchByAuthor("tolstoy", 0.5)
+	if len(res) != 2 {
+		t.Fatalf("unexpected results: %v", res)
+	}
+	if !res[0].Author.Equals(atolstoy) || res[0].Score != 1 || len(res[0].Books) != 1 {
+		t.Fatalf("unexpected result: %v", res[0])
+	}
+	if !res[1].Author.Equals(tolstoy) || len(res[1].Books) != 2 || res[1].Books[1].LibId != 3 {
+		t.Fatalf("unexpected result: %v", res[1])
+	}
+	if res = idx.SearchByAuthor("tol", 0); len(res) != 3 || res[2].Score != 0.8 {
+		t.Fatalf("unexpected results: %v", res)
+	}
+}
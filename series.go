@@ -0,0 +1,97 @@
+package inpx
+
+import "sort"
+
+// BooksBySeries groups books of the index by series name. Books in each series are sorted
+// by their number in the series. Deleted books and books without series are skipped.
+func (idx *Index) BooksBySeries() map[string][]Book {
+	out := make(map[string][]Book)
+	for _, b := range idx.AllBooks() {
+		if b.Deleted || b.Series == "" {
+			continue
+		}
+		out[b.Series] = append(out[b.Series], b)
+	}
+	for _, books := range out {
+		sort.SliceStable(books, func(i, j int) bool {
+			return books[i].SeriesNum < books[j].SeriesNum
+		})
+	}
+	return out
+}
+
+// seriesNeighbour returns a book that is next to b in its series, in a given direction.
+func (idx *Index) seriesNeighbour(b Book, dir int) (Book, bool) {
+	if b.SeriesNum == 0 || b.Series == "" {
+		return Book{}, false
+	}
+	books := idx.BooksBySeries()[b.Series]
+	for i := range books {
+		if books[i].LibId != b.LibId {
+			continue
+		}
+		j := i + dir
+		if j < 0 || j >= len(books) || books[j].SeriesNum == 0 {
+			return Book{}, false
+		}
+		return books[j], true
+	}
+	return Book{}, false
+}
+
+// PreviousInSeries returns a book that precedes b in its series.
+// It returns false if b is the first one or its position in the series is unknown.
+func (idx *Index) PreviousInSeries(b Book) (Book, bool) {
+	return idx.seriesNeighbour(b, -1)
+}
+
+// NextInSeries returns a book that follows b in its series.
+// It returns false if b is the last one or its position in the series is unknown.
+func (idx *Index) NextInSeries(b Book) (Book, bool) {
+	return idx.seriesNeighbour(b, +1)
+}
+
+// SeriesGap describes a series with missing volumes.
+type SeriesGap struct {
+	Series      string
+	MissingNums []int // sorted numbers of missing volumes
+}
+
+// maxSeriesGap is the maximal number of consecutive missing volumes reported by SeriesWithGaps.
+// Larger gaps are usually caused by wrong numbers in the records, so they are skipped.
+const maxSeriesGap = 100
+
+// SeriesWithGaps returns all series with missing volumes, sorted by series name. A volume is missing
+// if its number is between existing numbers of the series, but no book has it. Gaps of more than
+// 100 consecutive volumes are considered outliers and are not reported.
+// Books without a number in the series and deleted books are ignored.
+func (idx *Index) SeriesWithGaps() []SeriesGap {
+	var out []SeriesGap
+	for name, books := range idx.BooksBySeries() {
+		// books are sorted by number
+		var (
+			missing []int
+			prev    int
+			started bool
+		)
+		for _, b := range books {
+			n := b.SeriesNum
+			if n == 0 || (started && n == prev) {
+				continue
+			}
+			if started && n-prev-1 <= maxSeriesGap {
+				for m := prev + 1; m < n; m++ {
+					missing = append(missing, m)
+				}
+			}
+			prev, started = n, true
+		}
+		if len(missing) != 0 {
+			out = append(out, SeriesGap{Series: name, MissingNums: missing})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Series < out[j].Series
+	})
+	return out
+}
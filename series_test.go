@@ -0,0 +1,68 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeriesNavigation(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 3, Series: "S", SeriesNum: 3},
+			{LibId: 1, Series: "S", SeriesNum: 1},
+			{LibId: 4, Series: "S"},
+		},
+		"b": {
+			{LibId: 2, Series: "S", SeriesNum: 2},
+			{LibId: 5, Series: "Other", SeriesNum: 1},
+		},
+	}}
+	series := idx.BooksBySeries()
+	if len(series) != 2 || len(series["S"]) != 4 {
+		t.Fatalf("unexpected series: %v", series)
+	}
+	b2 := series["S"][2]
+	if b2.LibId != 2 {
+		t.Fatalf("unexpected order: %v", series["S"])
+	}
+	if b, ok := idx.PreviousInSeries(b2); !ok || b.LibId != 1 {
+		t.Fatalf("unexpected previous: %v, %v", b, ok)
+	}
+	if b, ok := idx.NextInSeries(b2); !ok || b.LibId != 3 {
+		t.Fatalf("unexpected next: %v, %v", b, ok)
+	}
+	if _, ok := idx.PreviousInSeries(series["S"][1]); ok {
+		t.Fatal("first book should have no previous one")
+	}
+	if _, ok := idx.NextInSeries(series["S"][3]); ok {
+		t.Fatal("last book should have no next one")
+	}
+	if _, ok := idx.NextInSeries(series["S"][0]); ok {
+		t.Fatal("book without number should have no next one")
+	}
+}
+
+func TestSeriesWithGaps(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"a": {
+			{LibId: 1, Series: "B", SeriesNum: 5},
+			{LibId: 2, Series: "B", SeriesNum: 1},
+			{LibId: 3, Series: "B", SeriesNum: 2},
+			{LibId: 4, Series: "B", SeriesNum: 0},
+			{LibId: 5, Series: "A", SeriesNum: 2},
+			{LibId: 6, Series: "A", SeriesNum: 4},
+			{LibId: 7, Series: "A", SeriesNum: 3, Deleted: true},
+			{LibId: 8, Series: "C", SeriesNum: 1},
+			{LibId: 9, Series: "C", SeriesNum: 2},
+			{LibId: 10, Series: "C", SeriesNum: 2000000000},
+			{LibId: 11, Series: "B", SeriesNum: 2},
+		},
+	}}
+	exp := []SeriesGap{
+		{Series: "A", MissingNums: []int{3}},
+		{Series: "B", MissingNums: []int{3, 4}},
+	}
+	if gaps := idx.SeriesWithGaps(); !reflect.DeepEqual(gaps, exp) {
+		t.Fatalf("unexpected gaps: %+v", gaps)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build go1.21
+
+package inpx
+
+import "log/slog"
+
+// WithLogger sets a logger for warnings about malformed records and unknown files in inpx,
+// instead of the standard logger. Records are only logged if WithErrorHandler is not set.
+// A nil logger discards all warnings.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *config) {
+		if l == nil {
+			c.logger = func(string, ...interface{}) {}
+			return
+		}
+		c.logger = l.Warn
+	}
+}
@@ -0,0 +1,43 @@
+//go:build go1.21
+
+package inpx
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWithLogger(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp":     "broken line\n",
+		"readme.md": "hello\n",
+	})
+	var buf bytes.Buffer
+	l := slog.New(slog.NewJSONHandler(&buf, nil))
+	if _, err := Open(path, WithLogger(l)); err != nil {
+		t.Fatal(err)
+	}
+	dec := json.NewDecoder(&buf)
+	var msgs []string
+	for dec.More() {
+		var rec map[string]interface{}
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec["level"] != "WARN" {
+			t.Fatalf("unexpected record: %v", rec)
+		}
+		msgs = append(msgs, rec["msg"].(string))
+		if rec["msg"] == "unknown file in inpx" && rec["name"] != "readme.md" {
+			t.Fatalf("unexpected record: %v", rec)
+		}
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("unexpected messages: %q", msgs)
+	}
+	if _, err := Open(path, WithLogger(nil)); err != nil {
+		t.Fatal(err)
+	}
+}
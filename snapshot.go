@@ -0,0 +1,160 @@
+package inpx
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	snapshotMagic   = "INPXSNAP"
+	snapshotVersion = 2
+)
+
+// snapshotAuthor mirrors Author. It is used because Author implements encoding.TextMarshaler,
+// which gob would use instead of encoding all name parts.
+type snapshotAuthor struct {
+	Name       []string
+	LastName   string
+	FirstName  string
+	MiddleName string
+}
+
+// snapshotBook mirrors Book with authors replaced by snapshotAuthor.
+type snapshotBook struct {
+	Authors   []snapshotAuthor
+	Genres    []string
+	Title     string
+	Series    string
+	SeriesNum int
+	File      File
+	LibId     int
+	Deleted   bool
+	Date      time.Time
+	Lang      string
+	LibRate   int
+	ISBN      string
+	Keywords  []string
+	Extra     []string
+	Raw       []byte
+}
+
+// snapshotHeader is encoded before the snapshot, so its version can be checked
+// before decoding the rest of the data.
+type snapshotHeader struct {
+	Version int
+}
+
+type snapshot struct {
+	Name        string
+	Description string
+	IndexVer    Version
+	Structure   []int
+	ExtraFields []string
+	Archives    map[string][]snapshotBook
+	Files       map[string]ArchiveFile
+}
+
+// Snapshot writes the index to w in a binary format that can be read back with RestoreSnapshot.
+// Restoring the snapshot is much faster than parsing the inpx file.
+func (idx *Index) Snapshot(w io.Writer) error {
+	if err := idx.LoadAll(); err != nil {
+		return err
+	}
+	s := snapshot{
+		Name:        idx.Name,
+		Description: idx.Description,
+		IndexVer:    idx.Version,
+		Structure:   idx.Structure,
+		ExtraFields: idx.ExtraFields,
+		Archives:    make(map[string][]snapshotBook, len(idx.Archives)),
+		Files:       idx.ArchiveFiles,
+	}
+	for name, books := range idx.Archives {
+		arr := make([]snapshotBook, len(books))
+		for i, b := range books {
+			sb := snapshotBook{
+				Genres: b.Genres, Title: b.Title,
+				Series: b.Series, SeriesNum: b.SeriesNum,
+				File: b.File, LibId: b.LibId, Deleted: b.Deleted,
+				Date: b.Date, Lang: b.Lang, LibRate: b.LibRate, ISBN: b.ISBN, Keywords: b.Keywords, Extra: b.Extra, Raw: b.Raw,
+			}
+			if b.Authors != nil {
+				sb.Authors = make([]snapshotAuthor, len(b.Authors))
+				for j, a := range b.Authors {
+					sb.Authors[j] = snapshotAuthor(a)
+				}
+			}
+			arr[i] = sb
+		}
+		s.Archives[name] = arr
+	}
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(snapshotMagic); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(bw)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion}); err != nil {
+		return fmt.Errorf("error while writing snapshot: %v", err)
+	}
+	if err := enc.Encode(&s); err != nil {
+		return fmt.Errorf("error while writing snapshot: %v", err)
+	}
+	return bw.Flush()
+}
+
+// RestoreSnapshot reads an index written by Index.Snapshot.
+// It returns ErrNoSignature if r is not a snapshot, and ErrUnsupportedFormat if the snapshot
+// was written in a different format version.
+func RestoreSnapshot(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic))
+	if _, err := io.ReadFull(br, magic); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	} else if string(magic) != snapshotMagic {
+		return nil, ErrNoSignature
+	}
+	dec := gob.NewDecoder(br)
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, fmt.Errorf("error while reading snapshot: %v", err)
+	}
+	if h.Version != snapshotVersion {
+		return nil, fmt.Errorf("%w: snapshot format version %d", ErrUnsupportedFormat, h.Version)
+	}
+	var s snapshot
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("error while reading snapshot: %v", err)
+	}
+	idx := &Index{
+		Name:         s.Name,
+		Description:  s.Description,
+		Version:      s.IndexVer,
+		Structure:    s.Structure,
+		ExtraFields:  s.ExtraFields,
+		Archives:     make(map[string][]Book, len(s.Archives)),
+		ArchiveFiles: s.Files,
+	}
+	for name, arr := range s.Archives {
+		books := make([]Book, len(arr))
+		for i, sb := range arr {
+			b := Book{
+				Genres: sb.Genres, Title: sb.Title,
+				Series: sb.Series, SeriesNum: sb.SeriesNum,
+				File: sb.File, LibId: sb.LibId, Deleted: sb.Deleted,
+				Date: sb.Date, Lang: sb.Lang, LibRate: sb.LibRate, ISBN: sb.ISBN, Keywords: sb.Keywords, Extra: sb.Extra, Raw: sb.Raw,
+			}
+			if sb.Authors != nil {
+				b.Authors = make([]Author, len(sb.Authors))
+				for j, a := range sb.Authors {
+					b.Authors[j] = Author(a)
+				}
+			}
+			books[i] = b
+		}
+		idx.Archives[name] = books
+	}
+	return idx, nil
+}
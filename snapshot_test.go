@@ -0,0 +1,60 @@
+package inpx
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	idx := makeTestIndex(100)
+	idx.Version = "20200101"
+	idx.Structure = DefaultStructure
+	for _, books := range idx.Archives {
+		for i := range books {
+			books[i].Authors = []Author{newAuthor([]string{"Doe", "John", "Jr"})}
+			books[i].Genres = []string{"sf"}
+			books[i].LibRate = -1
+		}
+	}
+	var buf bytes.Buffer
+	if err := idx.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	idx2, err := RestoreSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(idx, idx2) {
+		t.Fatal("restored index is different")
+	}
+
+	if _, err = RestoreSnapshot(bytes.NewReader([]byte("PK\x03\x04"))); !errors.Is(err, ErrNoSignature) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf.Reset()
+	buf.WriteString(snapshotMagic)
+	if err = gob.NewEncoder(&buf).Encode(snapshotHeader{Version: snapshotVersion + 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = RestoreSnapshot(&buf); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func BenchmarkRestoreSnapshot(b *testing.B) {
+	idx := makeTestIndex(100000)
+	var buf bytes.Buffer
+	if err := idx.Snapshot(&buf); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RestoreSnapshot(bytes.NewReader(buf.Bytes())); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package inpx
+
+import (
+	"sort"
+	"strings"
+)
+
+// leadingArticles lists articles that are ignored when sorting titles, by ISO 639-1 language code.
+// Articles ending with an apostrophe or a hyphen are attached to the next word.
+var leadingArticles = map[string][]string{
+	"en": {"the", "a", "an"},
+	"de": {"der", "die", "das", "den", "dem", "des", "ein", "eine", "einen", "einem", "einer", "eines"},
+	"fr": {"le", "la", "les", "l'", "un", "une", "des"},
+	"es": {"el", "la", "los", "las", "un", "una", "unos", "unas"},
+	"it": {"il", "lo", "la", "i", "gli", "le", "l'", "un", "uno", "una", "un'"},
+	"pt": {"o", "a", "os", "as", "um", "uma", "uns", "umas"},
+	"ca": {"el", "la", "els", "les", "l'", "un", "una"},
+	"nl": {"de", "het", "een", "'t"},
+	"sv": {"en", "ett", "den", "det"},
+	"da": {"en", "et", "den", "det"},
+	"no": {"en", "et", "ei", "den", "det"},
+	"is": {"hinn", "hin", "hið"},
+	"ro": {"un", "o"},
+	"hu": {"a", "az", "egy"},
+	"el": {"ο", "η", "το", "οι", "τα"},
+	"eo": {"la"},
+	"ga": {"an", "na"},
+	"cy": {"y", "yr"},
+	"tr": {"bir"},
+	"sq": {"një"},
+	"ar": {"al-", "el-"},
+	"ru": {"эль-", "аль-"},
+}
+
+// TitleSortKey returns a key for sorting titles: a lowercase title without a leading article
+// for a given language.
+func TitleSortKey(title, lang string) string {
+	key := strings.ToLower(strings.TrimSpace(title))
+	for _, art := range leadingArticles[NormalizeLang(lang)] {
+		if !strings.HasPrefix(key, art) {
+			continue
+		}
+		rest := key[len(art):]
+		if !strings.HasSuffix(art, "'") && !strings.HasSuffix(art, "-") {
+			if !strings.HasPrefix(rest, " ") {
+				continue
+			}
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			return rest
+		}
+	}
+	return key
+}
+
+// SortKey returns a key for sorting books by title. See TitleSortKey.
+//
+// AllBooks returns books in archive order; to sort them by title use:
+//
+//	sort.SliceStable(books, func(i, j int) bool {
+//		return books[i].SortKey() < books[j].SortKey()
+//	})
+func (b Book) SortKey() string {
+	return TitleSortKey(b.Title, b.Lang)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// NaturalLess compares strings in natural order: runs of digits are compared as numbers,
+// so "fb2-99" goes before "fb2-100". It can be used with sort.Slice.
+func NaturalLess(a, b string) bool {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				return a[i] < b[j]
+			}
+			i++
+			j++
+			continue
+		}
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		na := strings.TrimLeft(a[si:i], "0")
+		nb := strings.TrimLeft(b[sj:j], "0")
+		if len(na) != len(nb) {
+			return len(na) < len(nb)
+		}
+		if na != nb {
+			return na < nb
+		}
+	}
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+	// equal in natural order (e.g. different number of leading zeros)
+	return a < b
+}
+
+// SortedArchiveNames returns archive names of the index in natural order (see NaturalLess).
+func (idx *Index) SortedArchiveNames() []string {
+	names := idx.archiveNames()
+	sort.SliceStable(names, func(i, j int) bool {
+		return NaturalLess(names[i], names[j])
+	})
+	return names
+}
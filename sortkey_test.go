@@ -0,0 +1,54 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTitleSortKey(t *testing.T) {
+	for _, c := range []struct {
+		title, lang, exp string
+	}{
+		{"The Lord of the Rings", "en", "lord of the rings"},
+		{"A Tale of Two Cities", "eng", "tale of two cities"},
+		{"Theory", "en", "theory"},
+		{"The", "en", "the"},
+		{"L'Étranger", "fr", "étranger"},
+		{"Der Zauberberg", "de", "zauberberg"},
+		{"The Lord of the Rings", "ru", "the lord of the rings"},
+		{"Война и мир", "ru", "война и мир"},
+	} {
+		if got := TitleSortKey(c.title, c.lang); got != c.exp {
+			t.Errorf("TitleSortKey(%q, %q) = %q, expected %q", c.title, c.lang, got, c.exp)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	for _, c := range []struct {
+		a, b string
+		less bool
+	}{
+		{"fb2-00099", "fb2-000102", true},
+		{"fb2-000102", "fb2-00099", false},
+		{"fb2-1", "fb2-1", false},
+		{"fb2-1", "fb2-1a", true},
+		{"a2b10", "a2b9", false},
+		{"abc", "abd", true},
+		{"x", "10", false},
+	} {
+		if got := NaturalLess(c.a, c.b); got != c.less {
+			t.Errorf("NaturalLess(%q, %q) = %v", c.a, c.b, got)
+		}
+	}
+}
+
+func TestSortedArchiveNames(t *testing.T) {
+	idx := &Index{Archives: map[string][]Book{
+		"fb2-000102": nil, "fb2-00099": nil, "fb2-000001": nil, "usr-1": nil,
+	}}
+	exp := []string{"fb2-000001", "fb2-00099", "fb2-000102", "usr-1"}
+	if got := idx.SortedArchiveNames(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("unexpected order: %v", got)
+	}
+}
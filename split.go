@@ -0,0 +1,99 @@
+package inpx
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// SplitOptions sets limits for each output file of SplitINPXWithOptions. Zero values mean no limit,
+// but at least one limit must be set.
+type SplitOptions struct {
+	// MaxBooksPerFile is the maximal number of records in each file.
+	MaxBooksPerFile int
+	// MaxBytesPerFile is the maximal total size of book files (see File.Size) described by each file.
+	// A book that is larger than this limit is written to a separate file.
+	MaxBytesPerFile int64
+}
+
+// SplitINPX reads an inpx file and writes its records to a series of smaller inpx files in outDir,
+// each with at most maxBooksPerFile records. See SplitINPXWithOptions for details.
+func SplitINPX(path, outDir string, maxBooksPerFile int) ([]string, error) {
+	if maxBooksPerFile <= 0 {
+		return nil, fmt.Errorf("invalid number of books per file: %d", maxBooksPerFile)
+	}
+	return SplitINPXWithOptions(path, outDir, SplitOptions{MaxBooksPerFile: maxBooksPerFile})
+}
+
+// SplitINPXWithOptions reads an inpx file and writes its records to a series of smaller inpx files
+// in outDir, according to limits from opts. Records are kept in the original order, so each file
+// covers a consecutive range of archives. Output files are named after the source file with
+// a number suffix (e.g. "lib-001.inpx") and have the same collection, version and structure info.
+// It returns paths of created files.
+func SplitINPXWithOptions(path, outDir string, opts SplitOptions) ([]string, error) {
+	if opts.MaxBooksPerFile < 0 || opts.MaxBytesPerFile < 0 || (opts.MaxBooksPerFile == 0 && opts.MaxBytesPerFile == 0) {
+		return nil, fmt.Errorf("invalid split limits: %+v", opts)
+	}
+	r, err := NewIndexReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	hdr := r.Header()
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var (
+		paths []string
+		part  *Index
+		n     int
+		size  int64
+	)
+	flush := func() error {
+		if part == nil {
+			return nil
+		}
+		out := filepath.Join(outDir, fmt.Sprintf("%s-%03d.inpx", base, len(paths)+1))
+		if err := part.Save(out); err != nil {
+			return err
+		}
+		paths = append(paths, out)
+		part, n, size = nil, 0, 0
+		return nil
+	}
+	for {
+		pack, b, err := r.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return paths, err
+		}
+		if opts.MaxBytesPerFile > 0 && size+int64(b.File.Size) > opts.MaxBytesPerFile {
+			if err = flush(); err != nil {
+				return paths, err
+			}
+		}
+		if part == nil {
+			part = &Index{
+				Name:        hdr.Name,
+				Description: hdr.Description,
+				Version:     hdr.Version,
+				Structure:   hdr.Structure,
+				ExtraFields: hdr.ExtraFields,
+				Archives:    make(map[string][]Book),
+			}
+		}
+		part.Archives[pack] = append(part.Archives[pack], b)
+		n++
+		size += int64(b.File.Size)
+		if (opts.MaxBooksPerFile > 0 && n >= opts.MaxBooksPerFile) || (opts.MaxBytesPerFile > 0 && size >= opts.MaxBytesPerFile) {
+			if err = flush(); err != nil {
+				return paths, err
+			}
+		}
+	}
+	if err = flush(); err != nil {
+		return paths, err
+	}
+	return paths, nil
+}
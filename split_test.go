@@ -0,0 +1,88 @@
+package inpx
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSplitINPX(t *testing.T) {
+	dir := t.TempDir()
+	idx := makeTestIndex(25)
+	idx.Version = "20200101"
+	idx.Structure = append(append([]int{}, DefaultStructure...), FieldISBN)
+	src := filepath.Join(dir, "lib.inpx")
+	if err := idx.Save(src); err != nil {
+		t.Fatal(err)
+	}
+	out := t.TempDir()
+	paths, err := SplitINPX(src, out, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := []string{
+		filepath.Join(out, "lib-001.inpx"),
+		filepath.Join(out, "lib-002.inpx"),
+		filepath.Join(out, "lib-003.inpx"),
+	}
+	if !reflect.DeepEqual(paths, exp) {
+		t.Fatalf("unexpected paths: %q", paths)
+	}
+	var books []Book
+	for i, path := range paths {
+		part, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if part.Name != idx.Name || part.Version != idx.Version || !reflect.DeepEqual(part.Structure, idx.Structure) {
+			t.Fatalf("unexpected header: %q, %q, %v", part.Name, part.Version, part.Structure)
+		}
+		if n := part.TotalBooks(); (i < 2 && n != 10) || (i == 2 && n != 5) {
+			t.Fatalf("unexpected number of books in %s: %d", path, n)
+		}
+		books = append(books, part.AllBooks()...)
+	}
+	orig := idx.AllBooks()
+	if len(books) != len(orig) {
+		t.Fatalf("unexpected number of books: %d", len(books))
+	}
+	for i := range orig {
+		if !orig[i].MetaEqual(books[i]) {
+			t.Fatalf("unexpected book:\n%+v\nvs\n%+v", books[i], orig[i])
+		}
+	}
+}
+
+func TestSplitINPXBySize(t *testing.T) {
+	idx := &Index{Name: "test", Archives: map[string][]Book{"a": nil}}
+	for i, size := range []int{100, 100, 500, 50, 50} {
+		idx.Archives["a"] = append(idx.Archives["a"], Book{LibId: i + 1, File: File{Name: strconv.Itoa(i + 1), Ext: "fb2", Size: size}})
+	}
+	src := filepath.Join(t.TempDir(), "lib.inpx")
+	if err := idx.Save(src); err != nil {
+		t.Fatal(err)
+	}
+	paths, err := SplitINPXWithOptions(src, t.TempDir(), SplitOptions{MaxBytesPerFile: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parts [][]int
+	for _, path := range paths {
+		part, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, b := range part.AllBooks() {
+			ids = append(ids, b.LibId)
+		}
+		parts = append(parts, ids)
+	}
+	if exp := [][]int{{1, 2}, {3}, {4, 5}}; !reflect.DeepEqual(parts, exp) {
+		t.Fatalf("unexpected parts: %v", parts)
+	}
+	if _, err = SplitINPXWithOptions(src, t.TempDir(), SplitOptions{}); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,50 @@
+package inpx
+
+import "strings"
+
+// BooksTableSQL is a recommended definition of the SQL table for books. Column names match
+// struct tags of Book, so books can be scanned with sqlx. Authors, genres and keywords columns are filled
+// with Book.AuthorsStr, Book.GenresStr and Book.KeywordsStr; they are not mapped to Book fields and should be excluded
+// when scanning. The table is used by SQLiteExporter.
+const BooksTableSQL = `CREATE TABLE IF NOT EXISTS books (
+	lib_id                 INTEGER NOT NULL,
+	title                  TEXT NOT NULL DEFAULT '',
+	authors                TEXT NOT NULL DEFAULT '',
+	genres                 TEXT NOT NULL DEFAULT '',
+	series                 TEXT NOT NULL DEFAULT '',
+	series_num             INTEGER NOT NULL DEFAULT 0,
+	deleted                BOOLEAN NOT NULL DEFAULT FALSE,
+	date                   TIMESTAMP,
+	lang                   TEXT NOT NULL DEFAULT '',
+	lib_rate               INTEGER NOT NULL DEFAULT -1,
+	isbn                   TEXT NOT NULL DEFAULT '',
+	keywords               TEXT NOT NULL DEFAULT '',
+	"file.name"            TEXT NOT NULL,
+	"file.ext"             TEXT NOT NULL DEFAULT '',
+	"file.dir"             TEXT NOT NULL DEFAULT '',
+	"file.archive"         TEXT NOT NULL,
+	"file.size"            INTEGER NOT NULL DEFAULT 0,
+	"file.offset"          INTEGER NOT NULL DEFAULT 0,
+	"file.compressed_size" INTEGER NOT NULL DEFAULT 0,
+	"file.method"          INTEGER NOT NULL DEFAULT 0
+)`
+
+// AuthorsStr returns full names of book authors separated by semicolons.
+// It is used to store authors in SQL tables (see BooksTableSQL).
+func (b Book) AuthorsStr() string {
+	names := make([]string, 0, len(b.Authors))
+	for _, a := range b.Authors {
+		names = append(names, a.FullName())
+	}
+	return strings.Join(names, ";")
+}
+
+// GenresStr returns book genres separated by semicolons. See AuthorsStr for details.
+func (b Book) GenresStr() string {
+	return strings.Join(b.Genres, ";")
+}
+
+// KeywordsStr returns book keywords separated by semicolons. See AuthorsStr for details.
+func (b Book) KeywordsStr() string {
+	return strings.Join(b.Keywords, ";")
+}
@@ -0,0 +1,90 @@
+package inpx
+
+import "container/heap"
+
+// rankedBook is a book with its position in Index.AllBooks, used to break ties.
+type rankedBook struct {
+	book Book
+	seq  int
+}
+
+// bookHeap is a min-heap of books with the lowest ranked book at the root.
+type bookHeap struct {
+	books   []rankedBook
+	greater func(a, b Book) bool
+}
+
+// ranksHigher checks if the book a should be returned before b.
+func (h *bookHeap) ranksHigher(a, b rankedBook) bool {
+	if h.greater(a.book, b.book) {
+		return true
+	} else if h.greater(b.book, a.book) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (h *bookHeap) Len() int           { return len(h.books) }
+func (h *bookHeap) Less(i, j int) bool { return h.ranksHigher(h.books[j], h.books[i]) }
+func (h *bookHeap) Swap(i, j int)      { h.books[i], h.books[j] = h.books[j], h.books[i] }
+func (h *bookHeap) Push(x interface{}) { h.books = append(h.books, x.(rankedBook)) }
+func (h *bookHeap) Pop() interface{} {
+	b := h.books[len(h.books)-1]
+	h.books = h.books[:len(h.books)-1]
+	return b
+}
+
+// topBooks returns at most n books that are not deleted with the highest rank according to greater.
+// Books are sorted by rank in descending order; books with the same rank are returned in the same
+// order as Index.AllBooks. It keeps a heap of n books instead of sorting all books of the index.
+func (idx *Index) topBooks(n int, greater func(a, b Book) bool) []Book {
+	if n <= 0 {
+		return nil
+	}
+	h := &bookHeap{books: make([]rankedBook, 0, n), greater: greater}
+	seq := 0
+	for _, name := range idx.archiveNames() {
+		for _, b := range idx.Archives[name] {
+			if b.Deleted {
+				continue
+			}
+			rb := rankedBook{book: b, seq: seq}
+			seq++
+			if h.Len() < n {
+				heap.Push(h, rb)
+			} else if h.ranksHigher(rb, h.books[0]) {
+				h.books[0] = rb
+				heap.Fix(h, 0)
+			}
+		}
+	}
+	out := make([]Book, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(rankedBook).book
+	}
+	return out
+}
+
+// RecentlyAdded returns at most n newest books (see Book.Date), sorted from the newest one.
+// Deleted books are skipped.
+func (idx *Index) RecentlyAdded(n int) []Book {
+	return idx.topBooks(n, func(a, b Book) bool {
+		return a.Date.After(b.Date)
+	})
+}
+
+// Largest returns at most n books with the largest file size, sorted by size in descending order.
+// Deleted books are skipped.
+func (idx *Index) Largest(n int) []Book {
+	return idx.topBooks(n, func(a, b Book) bool {
+		return a.File.Size > b.File.Size
+	})
+}
+
+// HighestRated returns at most n books with the highest LibRate, sorted by rating in descending order.
+// Deleted books are skipped; books that are not rated are returned last.
+func (idx *Index) HighestRated(n int) []Book {
+	return idx.topBooks(n, func(a, b Book) bool {
+		return a.LibRate > b.LibRate
+	})
+}
@@ -0,0 +1,65 @@
+package inpx
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// sortTopBooks is a reference implementation of Index.topBooks that sorts all books.
+func sortTopBooks(idx *Index, n int, greater func(a, b Book) bool) []Book {
+	books := idx.Filter(func(b Book) bool { return !b.Deleted })
+	sort.SliceStable(books, func(i, j int) bool { return greater(books[i], books[j]) })
+	if n < len(books) {
+		books = books[:n]
+	}
+	return books
+}
+
+func TestTopBooks(t *testing.T) {
+	idx := makeTestIndex(1000)
+	for _, books := range idx.Archives {
+		for i := range books {
+			books[i].LibRate = books[i].LibId % 6
+		}
+	}
+	for _, c := range []struct {
+		name    string
+		fn      func(n int) []Book
+		greater func(a, b Book) bool
+	}{
+		{"recent", idx.RecentlyAdded, func(a, b Book) bool { return a.Date.After(b.Date) }},
+		{"largest", idx.Largest, func(a, b Book) bool { return a.File.Size > b.File.Size }},
+		{"rated", idx.HighestRated, func(a, b Book) bool { return a.LibRate > b.LibRate }},
+	} {
+		for _, n := range []int{0, 1, 10, 2000} {
+			t.Run(c.name+"-"+strconv.Itoa(n), func(t *testing.T) {
+				got := c.fn(n)
+				exp := sortTopBooks(idx, n, c.greater)
+				if n == 0 {
+					exp = nil
+				}
+				if !reflect.DeepEqual(got, exp) {
+					t.Fatalf("unexpected books:\n%v\nvs\n%v", got, exp)
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkRecentlyAdded(b *testing.B) {
+	idx := makeTestIndex(100000)
+	b.Run("heap", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			idx.RecentlyAdded(10)
+		}
+	})
+	b.Run("sort", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sortTopBooks(idx, 10, func(a, b Book) bool { return a.Date.After(b.Date) })
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package inpx
+
+import (
+	"strings"
+	"unicode"
+)
+
+// cyrillicToLatin maps lowercase Cyrillic letters to Latin according to GOST 7.79-2000 (system B).
+// Letter "ц" is handled separately, see transliterate.
+var cyrillicToLatin = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "j", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "x", 'ц': "cz",
+	'ч': "ch", 'ш': "sh", 'щ': "shh", 'ъ': "``", 'ы': "y`", 'ь': "`", 'э': "e`", 'ю': "yu",
+	'я': "ya",
+	// Ukrainian and Belarusian letters
+	'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g`", 'ў': "u`",
+}
+
+// transliterate converts Cyrillic letters of the string to Latin. Other characters are kept as is.
+func transliterate(s string) string {
+	r := []rune(s)
+	var sb strings.Builder
+	for i, c := range r {
+		lc := unicode.ToLower(c)
+		lat, ok := cyrillicToLatin[lc]
+		if !ok {
+			sb.WriteRune(c)
+			continue
+		}
+		if lc == 'ц' && i+1 < len(r) {
+			// "c" is used before "е", "и", "ы", "й" and "і"
+			switch unicode.ToLower(r[i+1]) {
+			case 'е', 'и', 'ы', 'й', 'і':
+				lat = "c"
+			}
+		}
+		if lc != c {
+			lat = strings.ToUpper(lat[:1]) + lat[1:]
+		}
+		sb.WriteString(lat)
+	}
+	return sb.String()
+}
+
+// Transliterate returns a copy of the author with Cyrillic letters in all name parts converted
+// to Latin according to GOST 7.79-2000 (system B). The result only has ASCII characters,
+// unless the name contains non-Cyrillic letters outside of ASCII.
+func (a Author) Transliterate() Author {
+	out := Author{
+		LastName:   transliterate(a.LastName),
+		FirstName:  transliterate(a.FirstName),
+		MiddleName: transliterate(a.MiddleName),
+	}
+	if a.Name != nil {
+		out.Name = make([]string, len(a.Name))
+		for i, p := range a.Name {
+			out.Name[i] = transliterate(p)
+		}
+	}
+	return out
+}
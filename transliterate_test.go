@@ -0,0 +1,32 @@
+package inpx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransliterate(t *testing.T) {
+	for _, c := range []struct {
+		in, exp string
+	}{
+		{"Толстой", "Tolstoj"},
+		{"Ёлкин", "Yolkin"},
+		{"Йошкар", "Joshkar"},
+		{"Подъячев", "Pod``yachev"},
+		{"Гоголь", "Gogol`"},
+		{"Цицерон", "Ciceron"},
+		{"Куницын", "Kunicy`n"},
+		{"Щукарь", "Shhukar`"},
+		{"Шелест", "Shelest"},
+		{"Doe", "Doe"},
+	} {
+		if got := transliterate(c.in); got != c.exp {
+			t.Errorf("%q: expected %q, got %q", c.in, c.exp, got)
+		}
+	}
+	a := newAuthor([]string{"Толстой", "Лев", "Николаевич"}).Transliterate()
+	exp := newAuthor([]string{"Tolstoj", "Lev", "Nikolaevich"})
+	if !reflect.DeepEqual(a, exp) {
+		t.Fatalf("unexpected author: %+v", a)
+	}
+}
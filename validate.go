@@ -0,0 +1,93 @@
+package inpx
+
+import (
+	"archive/zip"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ValidationReport describes problems found in an inpx file.
+type ValidationReport struct {
+	// TotalRecords is the number of records in all inp files, including malformed ones.
+	TotalRecords int
+	// ParseErrors lists records that cannot be parsed.
+	ParseErrors []ParseError
+	// MissingFiles lists books that are not deleted, but have no file in the archive.
+	MissingFiles []Book
+	// DuplicateLibIds lists LibIds that are used by more than one record.
+	DuplicateLibIds []int
+	// InvalidDates lists books with no date or with a date in the future.
+	InvalidDates []Book
+}
+
+// Validate reads an inpx file and checks it for problems. In addition to parsing, it checks
+// that book archives contain files for all records that are not deleted.
+// The report is returned even if an error occurs, and describes the part checked so far.
+func Validate(path string) (ValidationReport, error) {
+	var rep ValidationReport
+	idx, err := Open(path, WithErrorHandler(func(err error) {
+		var perr *ParseError
+		if errors.As(err, &perr) {
+			rep.ParseErrors = append(rep.ParseErrors, *perr)
+		}
+	}))
+	if err != nil {
+		return rep, err
+	}
+	rep.TotalRecords = idx.TotalBooks() + len(rep.ParseErrors)
+
+	now := time.Now()
+	ids := make(map[int]int)
+	for _, name := range idx.archiveNames() {
+		books := idx.Archives[name]
+		for _, b := range books {
+			if b.LibId != 0 {
+				ids[b.LibId]++
+			}
+			if b.Date.IsZero() || b.Date.After(now) {
+				rep.InvalidDates = append(rep.InvalidDates, b)
+			}
+		}
+		rep.MissingFiles = append(rep.MissingFiles, missingFiles(books)...)
+	}
+	for id, n := range ids {
+		if n > 1 {
+			rep.DuplicateLibIds = append(rep.DuplicateLibIds, id)
+		}
+	}
+	sort.Ints(rep.DuplicateLibIds)
+	return rep, nil
+}
+
+// missingFiles returns books that are not deleted and have no file. Books split across several
+// archives are reported if any part is missing. It works the same way as File.Exists,
+// but opens each archive only once.
+func missingFiles(books []Book) []Book {
+	var out []Book
+	archives := make(map[string]map[string]struct{}) // file names by archive path
+	for _, b := range books {
+		if b.Deleted {
+			continue
+		}
+		for _, part := range b.File.splitParts() {
+			path := part.AbsolutePath()
+			names, opened := archives[path]
+			if !opened {
+				if zf, err := zip.OpenReader(path); err == nil {
+					names = make(map[string]struct{}, len(zf.File))
+					for _, f := range zf.File {
+						names[f.Name] = struct{}{}
+					}
+					zf.Close()
+				}
+				archives[path] = names
+			}
+			if _, ok := names[part.zipName()]; !ok {
+				out = append(out, b)
+				break
+			}
+		}
+	}
+	return out
+}
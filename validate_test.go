@@ -0,0 +1,46 @@
+package inpx
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"fb2-001.inp": "A:\x04sf:\x04Title 1\x04\x04\x041\x04\x041\x04\x04fb2\x042010-01-01\x04\x04\x04\n" +
+			"A:\x04sf:\x04Title 2\x04\x04\x042\x04\x042\x04\x04fb2\x042010-01-01\x04\x04\x04\n" +
+			"A:\x04sf:\x04Deleted\x04\x04\x043\x04\x042\x041\x04fb2\x04\x04\x04\x04\n" +
+			"broken\n",
+		"fb2-002.inp": "A:\x04sf:\x04Title 4\x04\x04\x044\x04\x044\x04\x04fb2\x042010-01-01\x04\x04\x04\n",
+	})
+	writeTestZip(t, filepath.Join(filepath.Dir(path), "fb2-001.zip"), map[string]string{"1.fb2": "book"})
+	rep, err := Validate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.TotalRecords != 5 {
+		t.Fatalf("unexpected total: %d", rep.TotalRecords)
+	}
+	if len(rep.ParseErrors) != 1 {
+		t.Fatalf("unexpected parse errors: %v", rep.ParseErrors)
+	}
+	perr := rep.ParseErrors[0]
+	if perr.Archive != "fb2-001" || perr.Line != 4 || string(perr.Raw) != "broken" || !errors.Is(&perr, ErrTruncated) {
+		t.Fatalf("unexpected parse error: %+v", perr)
+	}
+	var missing []int
+	for _, b := range rep.MissingFiles {
+		missing = append(missing, b.LibId)
+	}
+	if !reflect.DeepEqual(missing, []int{2, 4}) {
+		t.Fatalf("unexpected missing files: %v", missing)
+	}
+	if !reflect.DeepEqual(rep.DuplicateLibIds, []int{2}) {
+		t.Fatalf("unexpected duplicates: %v", rep.DuplicateLibIds)
+	}
+	if len(rep.InvalidDates) != 1 || rep.InvalidDates[0].Title != "Deleted" {
+		t.Fatalf("unexpected invalid dates: %v", rep.InvalidDates)
+	}
+}
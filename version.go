@@ -0,0 +1,56 @@
+package inpx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is a version of the library index from version.info file. It is usually a date
+// in "YYYYMMDD" form, but some generators write dotted versions like "1.3" or "2.0.1".
+type Version string
+
+// parseVersion parses the contents of version.info file.
+func parseVersion(data []byte) Version {
+	return Version(strings.TrimSpace(stripBOM(string(data))))
+}
+
+// String returns the version as is.
+func (v Version) String() string {
+	return string(v)
+}
+
+// Major returns the first numeric component of the version, or 0 if it is not a number.
+func (v Version) Major() int {
+	s := string(v)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// Less checks if the version is lower than the other one. Versions are compared by dot-separated
+// components, numerically if both components are numbers. Missing components are treated as 0.
+func (v Version) Less(other Version) bool {
+	a, b := strings.Split(string(v), "."), strings.Split(string(other), ".")
+	for i := 0; i < len(a) || i < len(b); i++ {
+		pa, pb := "0", "0"
+		if i < len(a) {
+			pa = a[i]
+		}
+		if i < len(b) {
+			pb = b[i]
+		}
+		na, erra := strconv.Atoi(pa)
+		nb, errb := strconv.Atoi(pb)
+		switch {
+		case erra == nil && errb == nil:
+			if na != nb {
+				return na < nb
+			}
+		case pa != pb:
+			return pa < pb
+		}
+	}
+	return false
+}
@@ -0,0 +1,404 @@
+package inpx
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parts returns author's name parts in inp order. Parts are taken from LastName, FirstName and MiddleName.
+// The deprecated Name is used if these fields are empty, or if it still matches them
+// (to keep middle names split the same way as in the original file).
+func (a Author) parts() []string {
+	if a.LastName == "" && a.FirstName == "" && a.MiddleName == "" {
+		return a.Name
+	}
+	if len(a.Name) != 0 && newAuthor(append([]string(nil), a.Name...)).sameName(a) {
+		return a.Name
+	}
+	parts := []string{a.LastName, a.FirstName, a.MiddleName}
+	for len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+// sameName reports whether both authors have the same last, first and middle names.
+func (a Author) sameName(b Author) bool {
+	return a.LastName == b.LastName && a.FirstName == b.FirstName && a.MiddleName == b.MiddleName
+}
+
+// EncodeBook encodes a book as a line of inp file, including the trailing newline.
+// Fields are written in the given structure order and separated with 0x04.
+// Author name parts are joined with ',' and each author (and genre) is terminated with ':',
+// as in inp files. Dates are written as "2006-01-02", the deleted flag is written as "0" or "1".
+// Values of unknown fields are taken from Book.Extra.
+//
+// Field and line separator bytes are removed from field values, so every book is encoded
+// as a single valid record.
+func EncodeBook(b Book, structure []int) []byte {
+	return encodeBook(b, structure, DefaultFieldSeparator, DefaultLineSeparator)
+}
+
+// encodeBook is like EncodeBook, but allows to set custom field and line separators.
+func encodeBook(b Book, structure []int, fieldSep, lineSep byte) []byte {
+	fields := make([][]byte, 0, len(structure))
+	extra := b.Extra
+	for _, f := range structure {
+		var v string
+		switch f {
+		case FieldAuthor:
+			for _, a := range b.Authors {
+				v += strings.Join(a.parts(), ",") + ":"
+			}
+		case FieldGenre:
+			for _, g := range b.Genres {
+				v += g + ":"
+			}
+		case FieldTitle:
+			v = b.Title
+		case FieldSeries:
+			v = b.Series
+		case FieldSeriesNum:
+			v = itoa(b.SeriesNum)
+		case FieldFileName:
+			v = b.File.Name
+		case FieldFileSize:
+			v = itoa(b.File.Size)
+		case FieldLibId:
+			v = itoa(b.LibId)
+		case FieldDeleted:
+			v = "0"
+			if b.Deleted {
+				v = "1"
+			}
+		case FieldExt:
+			v = b.File.Ext
+		case FieldDate:
+			if !b.Date.IsZero() {
+				v = b.Date.Format("2006-01-02")
+			}
+		case FieldLang:
+			v = b.Lang
+		case FieldLibRate:
+			if b.LibRate >= 0 {
+				v = strconv.Itoa(b.LibRate)
+			}
+		case FieldKeywords:
+			v = strings.Join(b.Keywords, ",")
+		case FieldISBN:
+			v = b.ISBN
+		case FieldFolder:
+			v = strings.Join(append([]string{b.File.Archive}, b.File.AdditionalArchives...), ",")
+		case fieldUnknown:
+			if len(extra) != 0 {
+				v, extra = extra[0], extra[1:]
+			}
+		}
+		fields = append(fields, stripSeparators(v, fieldSep, lineSep))
+	}
+	line := bytes.Join(fields, []byte{fieldSep})
+	return append(line, lineSep)
+}
+
+// stripSeparators removes field and line separator bytes from the field value.
+func stripSeparators(v string, fieldSep, lineSep byte) []byte {
+	if strings.IndexByte(v, fieldSep) < 0 && strings.IndexByte(v, lineSep) < 0 {
+		return []byte(v)
+	}
+	out := make([]byte, 0, len(v))
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c != fieldSep && c != lineSep {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// itoa formats an integer field. Zero values are written as empty strings.
+func itoa(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// Writer writes library index in inpx format.
+type Writer struct {
+	zw *zip.Writer
+	c  *config
+
+	// set by AppendToExisting
+	base    *Index
+	path    string
+	f       *os.File
+	written map[string]bool // names of files written to the zip
+
+	// inp file started by AddArchive
+	cur          io.Writer
+	curStructure []int
+}
+
+// NewWriter creates a new inpx writer. Books are written using DefaultStructure,
+// unless a different one is set with WithStructure or WriteStructure. WithFieldSeparator
+// and WithLineSeparator options are supported as well, other options are ignored.
+//
+// Note that WithStructure does not write structure.info, so the structure must be
+// known to readers of the file; use WriteStructure to store it in the file.
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	return &Writer{
+		zw: zip.NewWriter(w),
+		c:  newConfig(opts),
+	}
+}
+
+// create adds a new file to the inpx zip.
+func (w *Writer) create(name string) (io.Writer, error) {
+	if w.written == nil {
+		w.written = make(map[string]bool)
+	}
+	w.written[name] = true
+	w.cur, w.curStructure = nil, nil
+	return w.zw.Create(name)
+}
+
+func (w *Writer) writeFile(name string, data []byte) error {
+	fw, err := w.create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(data)
+	return err
+}
+
+// WriteCollectionInfo writes collection.info file with the name and the description of the library.
+func (w *Writer) WriteCollectionInfo(name, desc string) error {
+	data := name + "\n"
+	if desc != "" {
+		data += desc + "\n"
+	}
+	return w.writeFile("collection.info", []byte(data))
+}
+
+// WriteStructure writes structure.info file with a given field order.
+// Archives written after this call use the same structure.
+//
+// Names of fields that are not known to the package (see Index.ExtraFields) are taken from extra,
+// in order of their appearance in the structure. Their values are written from Book.Extra.
+func (w *Writer) WriteStructure(structure []int, extra ...string) error {
+	names := make([]string, 0, len(structure))
+	for _, f := range structure {
+		name, ok := FieldName[f]
+		if !ok && len(extra) != 0 {
+			name, extra = extra[0], extra[1:]
+		} else if !ok {
+			name = "UNKNOWN"
+		}
+		names = append(names, name)
+	}
+	if err := w.writeFile("structure.info", []byte(strings.Join(names, ";")+"\n")); err != nil {
+		return err
+	}
+	w.c.structure = structure
+	return nil
+}
+
+// writeCustomStructure writes structure.info if the structure differs from DefaultStructure.
+func (w *Writer) writeCustomStructure(structure []int, extra []string) error {
+	if equalInts(structure, DefaultStructure) {
+		w.c.structure = structure
+		return nil
+	}
+	return w.WriteStructure(structure, extra...)
+}
+
+// WriteVersion writes version.info file.
+func (w *Writer) WriteVersion(v Version) error {
+	return w.writeFile("version.info", []byte(string(v)+"\n"))
+}
+
+// WriteArchive writes an inp file that describes books in a given archive.
+// If the writer was created with AppendToExisting, books are added after existing books of the archive.
+func (w *Writer) WriteArchive(name string, books []Book) error {
+	return w.writeArchive(name, books, w.c.structure)
+}
+
+func (w *Writer) writeArchive(name string, books []Book, structure []int) error {
+	fw, err := w.openArchive(name, structure)
+	if err != nil {
+		return err
+	}
+	for _, b := range books {
+		if _, err = fw.Write(encodeBook(b, structure, w.c.fieldSep, w.c.lineSep)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openArchive creates an inp file for the archive and writes existing books of the archive
+// if the writer was created with AppendToExisting.
+func (w *Writer) openArchive(name string, structure []int) (io.Writer, error) {
+	fw, err := w.create(name + ".inp")
+	if err != nil {
+		return nil, err
+	}
+	if w.base != nil {
+		for _, b := range w.base.Archives[name] {
+			if _, err = fw.Write(encodeBook(b, structure, w.c.fieldSep, w.c.lineSep)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return fw, nil
+}
+
+// AddArchive starts an inp file for a given archive. Books are added to it one by one with AddBook,
+// until the next archive is started or any other file is written.
+// If the writer was created with AppendToExisting, existing books of the archive are written first.
+func (w *Writer) AddArchive(name string) error {
+	fw, err := w.openArchive(name, w.c.structure)
+	if err != nil {
+		return err
+	}
+	w.cur, w.curStructure = fw, w.c.structure
+	return nil
+}
+
+// AddBook adds a book to the archive started by AddArchive.
+func (w *Writer) AddBook(b Book) error {
+	if w.cur == nil {
+		return errors.New("no archive to add the book to")
+	}
+	_, err := w.cur.Write(encodeBook(b, w.curStructure, w.c.fieldSep, w.c.lineSep))
+	return err
+}
+
+// Close finishes writing the inpx file. It does not close the underlying writer.
+//
+// If the writer was created with AppendToExisting, Close writes all existing archives
+// that were not written yet and replaces the original file.
+func (w *Writer) Close() error {
+	if w.base == nil {
+		return w.zw.Close()
+	}
+	err := w.closeAppend()
+	if err != nil {
+		w.f.Close()
+		os.Remove(w.f.Name())
+		return err
+	}
+	return nil
+}
+
+func (w *Writer) closeAppend() error {
+	idx := w.base
+	if !w.written["collection.info"] {
+		if err := w.WriteCollectionInfo(idx.Name, idx.Description); err != nil {
+			return fmt.Errorf("error while writing collection info: %v", err)
+		}
+	}
+	if !w.written["version.info"] {
+		if err := w.WriteVersion(idx.Version); err != nil {
+			return fmt.Errorf("error while writing version info: %v", err)
+		}
+	}
+	if !w.written["structure.info"] {
+		if err := w.writeCustomStructure(w.c.structure, idx.ExtraFields); err != nil {
+			return fmt.Errorf("error while writing structure info: %v", err)
+		}
+	}
+	for _, name := range idx.archiveNames() {
+		if w.written[name+".inp"] {
+			continue
+		}
+		if err := w.writeArchive(name, nil, w.c.structure); err != nil {
+			return fmt.Errorf("error while writing inp: %v", err)
+		}
+	}
+	if err := w.zw.Close(); err != nil {
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.f.Name(), w.path)
+}
+
+// AppendToExisting reads an existing inpx file and returns a writer that adds new records to it.
+// Options are used for reading the index, except the ones that filter what is read
+// (WithIncludeArchives, WithExcludeArchives and WithLoadFields): they are ignored, so existing
+// records are never lost. Only WithFieldSeparator and WithLineSeparator are used for writing.
+//
+// The file is rewritten from scratch: records are written to a temporary file that replaces
+// the original one on Close. Collection info, version info, structure info and all existing archives
+// are preserved, unless they are written explicitly. Records are written using the structure
+// of the existing file.
+func AppendToExisting(path string, opts ...Option) (*Writer, error) {
+	c := newConfig(opts)
+	idx, err := Open(path, append(opts, func(c *config) {
+		c.include, c.exclude, c.loadFields = nil, nil, nil
+	})...)
+	if err != nil {
+		return nil, err
+	}
+	err = idx.LoadAll()
+	if cerr := idx.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	w := NewWriter(f, WithFieldSeparator(c.fieldSep), WithLineSeparator(c.lineSep))
+	w.base, w.path, w.f = idx, path, f
+	if idx.Structure != nil {
+		w.c.structure = idx.Structure
+	}
+	return w, nil
+}
+
+// Save writes the index to a new inpx file. Books are written using the structure of the index
+// (DefaultStructure if it is not set); structure.info is written if it differs from DefaultStructure.
+func (idx *Index) Save(path string) error {
+	if err := idx.LoadAll(); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	structure := idx.Structure
+	if structure == nil {
+		structure = DefaultStructure
+	}
+	w := NewWriter(f)
+	if err = w.WriteCollectionInfo(idx.Name, idx.Description); err != nil {
+		return fmt.Errorf("error while writing collection info: %v", err)
+	}
+	if err = w.WriteVersion(idx.Version); err != nil {
+		return fmt.Errorf("error while writing version info: %v", err)
+	}
+	if err = w.writeCustomStructure(structure, idx.ExtraFields); err != nil {
+		return fmt.Errorf("error while writing structure info: %v", err)
+	}
+	for _, name := range idx.archiveNames() {
+		if err = w.WriteArchive(name, idx.Archives[name]); err != nil {
+			return fmt.Errorf("error while writing inp: %v", err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
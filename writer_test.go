@@ -0,0 +1,460 @@
+package inpx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSaveRoundTrip(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"collection.info": "\ufeffTest collection\r\nhttp://example.com\nUpdated weekly\n\n",
+		"version.info":    "20200101\n",
+		"fb2-001.inp": "Толстой,Лев,Николаевич:\x04prose_classic:prose_rus_classic:\x04Война и мир\x04Эпопея\x041\x0410\x041234\x0410\x04\x04fb2\x042010-01-02\x04ru\x044\x04\x04\n" +
+			"Doe,John:Roe,Jane:\x04sf:\x04Title\x04\x04\x04111\x0420\x0411\x041\x04epub\x04\x04en\x040\x04\x04\n",
+		"fb2-002.inp": "Author:\x04det:\x04Other\x04\x04\x04x\x04\x0412\x04\x04txt\x042020-12-31\x04\x04\x04\x04\n",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Name != "Test collection" || index.Description != "http://example.com\nUpdated weekly" {
+		t.Fatalf("unexpected collection info: %q, %q", index.Name, index.Description)
+	}
+	if rates := []int{index.Archives["fb2-001"][0].LibRate, index.Archives["fb2-001"][1].LibRate, index.Archives["fb2-002"][0].LibRate}; !reflect.DeepEqual(rates, []int{4, 0, -1}) {
+		t.Fatalf("unexpected rates: %v", rates)
+	}
+	if index.TotalBooks() != 3 {
+		t.Fatalf("unexpected books: %v", index.Archives)
+	}
+	out := filepath.Join(filepath.Dir(path), "out.inpx")
+	if err = index.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	index2, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index, index2) {
+		t.Fatalf("index changed after round trip:\n%+v\nvs\n%+v", index, index2)
+	}
+}
+
+func TestEncodeBook(t *testing.T) {
+	b := Book{
+		LibRate: -1,
+		Authors: []Author{newAuthor([]string{"Doe", "John"}), {LastName: "Roe", FirstName: "Jane"}},
+		Genres:  []string{"sf", "det"},
+		Title:   "Title",
+		LibId:   42,
+		Deleted: true,
+		Date:    testDate,
+		File:    File{Name: "42", Ext: "fb2", Size: 100},
+	}
+	line := string(EncodeBook(b, DefaultStructure))
+	exp := "Doe,John:Roe,Jane:\x04sf:det:\x04Title\x04\x04\x0442\x04100\x0442\x041\x04fb2\x042020-01-01\x04\x04\x04\n"
+	if line != exp {
+		t.Fatalf("unexpected line:\n%q\nvs\n%q", line, exp)
+	}
+	line = string(EncodeBook(b, []int{FieldLibId, FieldTitle}))
+	if exp = "42\x04Title\n"; line != exp {
+		t.Fatalf("unexpected line: %q", line)
+	}
+	b.Authors = []Author{
+		newAuthor([]string{"Толстой", "Лев", "Николаевич", "Граф"}),
+		newAuthor([]string{"Doe", "John"}),
+		{Name: []string{"Anonymous"}},
+	}
+	b.Authors[1].LastName, b.Authors[1].MiddleName = "Roe", "J."
+	line = string(EncodeBook(b, []int{FieldAuthor}))
+	if exp = "Толстой,Лев,Николаевич,Граф:Roe,John,J.:Anonymous:\n"; line != exp {
+		t.Fatalf("unexpected line: %q", line)
+	}
+}
+
+func TestEncodeBookSeparators(t *testing.T) {
+	b := Book{
+		Title:    "Multi\nline\x04title",
+		Series:   "Se\x04ries",
+		Keywords: []string{"a\nb", "c"},
+		Authors:  []Author{{LastName: "Doe\n", FirstName: "Jo\x04hn"}},
+		LibId:    1,
+	}
+	structure := []int{FieldTitle, FieldSeries, FieldKeywords, FieldAuthor, FieldLibId}
+	line := EncodeBook(b, structure)
+	if exp := "Multilinetitle\x04Series\x04ab,c\x04Doe,John:\x041\n"; string(line) != exp {
+		t.Fatalf("unexpected line: %q", line)
+	}
+	path := writeTestInpx(t, map[string]string{"a.inp": string(line) + string(EncodeBook(b, structure))})
+	index, err := Open(path, WithStructure(structure))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if books := index.Archives["a"]; len(books) != 2 || len(index.ParseErrors) != 0 || books[0].Title != "Multilinetitle" {
+		t.Fatalf("unexpected books: %v, errors: %v", books, index.ParseErrors)
+	}
+}
+
+func TestSeparators(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, WithFieldSeparator('|'), WithLineSeparator(';'), WithStructure([]int{FieldLibId, FieldTitle}))
+	books := []Book{{LibId: 1, Title: "A"}, {LibId: 2, Title: "B"}}
+	if err := w.WriteArchive("fb2-001", books); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenReaderAt(bytes.NewReader(buf.Bytes()), int64(buf.Len()),
+		WithFieldSeparator('|'), WithLineSeparator(';'), WithStructure([]int{FieldLibId, FieldTitle}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := index.Archives["fb2-001"]
+	if len(got) != 2 || got[0].LibId != 1 || got[1].Title != "B" {
+		t.Fatalf("unexpected books: %+v", got)
+	}
+
+	path := writeTestInpx(t, map[string]string{
+		"fb2-001.inp": "1\x04A\r\n2\x04B\r\n",
+	})
+	index, err = OpenWithStructure(path, []int{FieldLibId, FieldTitle})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = index.Archives["fb2-001"]
+	if len(got) != 2 || got[0].Title != "A" || got[1].Title != "B" {
+		t.Fatalf("unexpected books: %+v", got)
+	}
+}
+
+func TestAddFromDirectory(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"123.fb2": `<?xml version="1.0" encoding="utf-8"?>
+<FictionBook><description><title-info>
+<genre>sf</genre><author><first-name>John</first-name><last-name>Doe</last-name></author>
+<book-title>Title</book-title><lang>en</lang><sequence name="Series" number="2"/>
+</title-info></description><body/></FictionBook>`,
+		"Roe_Jane_Other_Title.epub": "epub",
+		"notes.md":                  "notes",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "out.inpx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWriter(f)
+	if err = w.AddFromDirectory(dir, "books", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	books := index.Archives["books"]
+	if len(books) != 2 {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+	b := books[0]
+	if b.LibId != 123 || b.Title != "Title" || b.Series != "Series" || b.SeriesNum != 2 || b.Lang != "en" ||
+		!reflect.DeepEqual(b.Genres, []string{"sf"}) || b.Authors[0].FullName() != "John Doe" || b.File.Ext != "fb2" {
+		t.Fatalf("unexpected fb2 book: %+v", b)
+	}
+	b = books[1]
+	if b.Title != "Other Title" || b.Authors[0].FullName() != "Jane Roe" || b.File.Name != "Roe_Jane_Other_Title" || b.File.Size != 4 {
+		t.Fatalf("unexpected epub book: %+v", b)
+	}
+}
+
+func TestWriterAddBook(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	if err := w.AddBook(Book{Title: "None"}); err == nil {
+		t.Fatal("expected an error without an archive")
+	}
+	if err := w.WriteCollectionInfo("Test", ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := w.AddArchive(name); err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 2; i++ {
+			if err := w.AddBook(Book{Title: name + strconv.Itoa(i), LibRate: -1}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.WriteVersion("1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddBook(Book{Title: "None"}); err == nil {
+		t.Fatal("expected an error after writing other file")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index, err := OpenBytes(buf.Bytes(), DefaultStructure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, b := range index.AllBooks() {
+		titles = append(titles, b.File.Archive+"/"+b.Title)
+	}
+	if exp := []string{"a/a1", "a/a2", "b/b1", "b/b2"}; !reflect.DeepEqual(titles, exp) {
+		t.Fatalf("unexpected books: %q", titles)
+	}
+}
+
+func TestSaveCustomStructure(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"structure.info": "AUTHOR;GENRE;TITLE;FILE;EXT;ISBN;FOLDER\n",
+		"a.inp":          "Author:\x04sf:\x04Title\x041\x04fb2\x04978-3-16-148410-0\x04a,b\n",
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := index.Archives["a"][0]
+	if b.ISBN != "978-3-16-148410-0" || b.File.Archive != "a" || !reflect.DeepEqual(b.File.AdditionalArchives, []string{"b"}) {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+	out := filepath.Join(filepath.Dir(path), "out.inpx")
+	if err = index.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	index2, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index, index2) {
+		t.Fatalf("index changed after round trip:\n%+v\nvs\n%+v", index, index2)
+	}
+	w, err := AppendToExisting(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteArchive("c", []Book{{Title: "C", ISBN: "123", File: File{Name: "2", Ext: "fb2", Archive: "c"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index3, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index3.Structure, index.Structure) || !reflect.DeepEqual(index3.Archives["a"], index.Archives["a"]) {
+		t.Fatalf("unexpected index after append: %+v", index3)
+	}
+	if books := index3.Archives["c"]; len(books) != 1 || books[0].ISBN != "123" {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+}
+
+func TestSaveMyHomeLibStructure(t *testing.T) {
+	line := "Толстой,Лев,Николаевич:\x04prose_classic:\x04Война и мир\x04\x04\x0410\x041234\x0410\x040\x04fb2\x042010-01-02\x0477\x04fb2-001\x04ru\x044\x04war,peace\n"
+	path := writeTestInpx(t, map[string]string{
+		"structure.info": "AUTHOR;GENRE;TITLE;SERIES;SERNO;FILE;SIZE;LIBID;DEL;EXT;DATE;INSNO;FOLDER;LANG;LIBRATE;KEYWORDS;\r\n",
+		"fb2-001.inp":    line,
+	})
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.ExtraFields, []string{"INSNO"}) {
+		t.Fatalf("unexpected extra fields: %q", index.ExtraFields)
+	}
+	if b := index.Archives["fb2-001"][0]; !reflect.DeepEqual(b.Extra, []string{"77"}) {
+		t.Fatalf("unexpected extra values: %q", b.Extra)
+	}
+	out := filepath.Join(filepath.Dir(path), "out.inpx")
+	if err = index.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		data, err := readZipFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	if s := files["structure.info"]; s != "AUTHOR;GENRE;TITLE;SERIES;SERNO;FILE;SIZE;LIBID;DEL;EXT;DATE;INSNO;FOLDER;LANG;LIBRATE;KEYWORDS\n" {
+		t.Fatalf("unexpected structure: %q", s)
+	}
+	if s := files["fb2-001.inp"]; s != line {
+		t.Fatalf("unexpected inp:\n%q\nvs\n%q", s, line)
+	}
+}
+
+func TestAppendToExisting(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"collection.info": "Test collection\n",
+		"version.info":    "20200101\n",
+		"a.inp":           "Author:\x04sf:\x04A1\x04\x04\x041\x04\x041\x04\x04fb2\x04\x04\x04\x04\n",
+		"b.inp":           "Author:\x04sf:\x04B1\x04\x04\x042\x04\x042\x04\x04fb2\x04\x04\x04\x04\n",
+	})
+	w, err := AppendToExisting(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteArchive("a", []Book{{Title: "A2", LibId: 3, File: File{Name: "3", Ext: "fb2"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteArchive("c", []Book{{Title: "C1", LibId: 4, File: File{Name: "4", Ext: "fb2"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Name != "Test collection" || index.Version != "20200101" {
+		t.Fatalf("unexpected header: %q, %q", index.Name, index.Version)
+	}
+	var titles []string
+	for _, b := range index.AllBooks() {
+		titles = append(titles, b.Title)
+	}
+	if exp := []string{"A1", "A2", "B1", "C1"}; !reflect.DeepEqual(titles, exp) {
+		t.Fatalf("unexpected books: %q", titles)
+	}
+	if files, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp")); len(files) != 0 {
+		t.Fatalf("temporary files are left: %q", files)
+	}
+}
+
+func TestAppendToExistingFilters(t *testing.T) {
+	path := writeTestInpx(t, map[string]string{
+		"a.inp": "Author:\x04sf:\x04A1\x04\x04\x041\x04\x041\x040\x04fb2\x042020-01-01\x04en\x04\x04\n",
+		"b.inp": "Author:\x04sf:\x04B1\x04\x04\x042\x04\x042\x040\x04fb2\x04\x04\x04\x04\n",
+	})
+	orig, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := AppendToExisting(path, WithIncludeArchives("a"), WithLoadFields(FieldTitle, FieldLibId))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.WriteArchive("c", []Book{{Title: "C1", LibId: 3, File: File{Name: "3", Ext: "fb2"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Archives) != 3 || len(index.Archives["c"]) != 1 {
+		t.Fatalf("unexpected archives: %v", index.Archives)
+	}
+	for _, name := range []string{"a", "b"} {
+		if !reflect.DeepEqual(index.Archives[name], orig.Archives[name]) {
+			t.Fatalf("archive %s changed:\n%+v\nvs\n%+v", name, index.Archives[name], orig.Archives[name])
+		}
+	}
+}
+
+func TestImportBookFile(t *testing.T) {
+	dir := t.TempDir()
+	writeTestZip(t, filepath.Join(dir, "a.zip"), map[string]string{"1.fb2": "old"})
+	src := filepath.Join(t.TempDir(), "new.epub")
+	if err := os.WriteFile(src, []byte("new book"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx := &Index{Archives: map[string][]Book{
+		"a": {{LibId: 1, File: File{Name: "1", Ext: "fb2", Dir: dir, Archive: "a"}}},
+		"b": {{LibId: 5}},
+	}}
+	if err := idx.ImportBookFile(src, "a", Book{Title: "New"}); err != nil {
+		t.Fatal(err)
+	}
+	books := idx.Archives["a"]
+	if len(books) != 2 {
+		t.Fatalf("unexpected books: %+v", books)
+	}
+	b := books[1]
+	exp := File{Name: "6", Ext: "epub", Dir: dir, Archive: "a", Size: 8}
+	if b.LibId != 6 || b.Title != "New" || !reflect.DeepEqual(b.File, exp) {
+		t.Fatalf("unexpected book: %+v", b)
+	}
+	for _, b := range books {
+		if ok, err := b.File.Exists(); err != nil || !ok {
+			t.Fatalf("file %s does not exist: %v", b.File.zipName(), err)
+		}
+	}
+	if err := idx.ImportBookFile(src, "a", Book{File: File{Name: "6"}}); err == nil {
+		t.Fatal("expected an error for a duplicate file")
+	}
+}
+
+func TestCopyBook(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("book content ", 100)
+	writeTestZip(t, filepath.Join(dir, "a.zip"), map[string]string{"1.fb2": content})
+	writeTestZip(t, filepath.Join(dir, "b.zip"), map[string]string{"2.fb2": "other"})
+	src := File{Name: "1", Ext: "fb2", Dir: dir, Archive: "a"}
+	method, rc, err := src.OpenCompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(flate.NewReader(rc))
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	} else if method != zip.Deflate || string(data) != content {
+		t.Fatalf("unexpected data: %d, %q", method, data)
+	}
+	dst := filepath.Join(dir, "b.zip")
+	if err = CopyBook(src, dst); err != nil {
+		t.Fatal(err)
+	}
+	for _, fr := range []File{{Name: "1", Ext: "fb2"}, {Name: "2", Ext: "fb2"}} {
+		fr.Dir, fr.Archive = dir, "b"
+		rc, err := fr.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		} else if fr.Name == "1" && string(data) != content {
+			t.Fatalf("unexpected content: %q", data)
+		}
+	}
+	if err = CopyBook(src, dst); err == nil {
+		t.Fatal("expected an error for a duplicate file")
+	}
+	if _, _, err = (File{Name: "3", Ext: "fb2", Dir: dir, Archive: "a"}).OpenCompressed(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}